@@ -0,0 +1,161 @@
+package vanadium_index
+
+import (
+	"encoding/binary"
+	"encoding/gob"
+	"math/bits"
+)
+
+type BinaryFlatIndex struct {
+	state *BinaryFlatIndexState
+}
+
+type BinaryFlatIndexState struct {
+	NumBits  int
+	NumBytes int
+	Data     []uint8
+}
+
+func newBinaryFlatIndex(numBits int) (*BinaryFlatIndex, error) {
+	if numBits <= 0 || numBits%8 != 0 {
+		return nil, ErrInvalidNumBits
+	}
+	return &BinaryFlatIndex{
+		state: &BinaryFlatIndexState{
+			NumBits:  numBits,
+			NumBytes: numBits / 8,
+			Data:     make([]uint8, 0),
+		},
+	}, nil
+}
+
+func loadBinaryFlatIndex(dec *gob.Decoder) (*BinaryFlatIndex, error) {
+	index := &BinaryFlatIndex{}
+	err := index.decode(dec)
+	if err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func (index *BinaryFlatIndex) Train(data []uint8) error {
+	return nil
+}
+
+func (index *BinaryFlatIndex) Add(data []uint8) error {
+	if len(data) == 0 {
+		return newIndexError(OpAdd, IndexTypeBinaryFlat, ErrEmptyData)
+	}
+
+	if len(data)%index.state.NumBytes != 0 {
+		return newIndexError(OpAdd, IndexTypeBinaryFlat, ErrInvalidDataLength, "dataLength", len(data), "numBytes", index.state.NumBytes)
+	}
+
+	index.state.Data = append(index.state.Data, data...)
+	return nil
+}
+
+func (index *BinaryFlatIndex) Search(query []uint8, k int) ([][]int, [][]int, error) {
+	if k <= 0 {
+		return nil, nil, newIndexError(OpSearch, IndexTypeBinaryFlat, ErrInvalidK, "k", k)
+	}
+
+	if len(query) == 0 {
+		return nil, nil, newIndexError(OpSearch, IndexTypeBinaryFlat, ErrEmptyData)
+	}
+
+	if len(query)%index.state.NumBytes != 0 {
+		return nil, nil, newIndexError(OpSearch, IndexTypeBinaryFlat, ErrInvalidDataLength, "dataLength", len(query), "numBytes", index.state.NumBytes)
+	}
+
+	N := len(index.state.Data) / index.state.NumBytes
+	numQueries := len(query) / index.state.NumBytes
+
+	results := make([][]int, numQueries)
+	distances := make([][]int, numQueries)
+	for q := range numQueries {
+		neighbors := NewSmallestK(k)
+		subQuery := query[q*index.state.NumBytes : (q+1)*index.state.NumBytes]
+		for n := range N {
+			subData := index.state.Data[n*index.state.NumBytes : (n+1)*index.state.NumBytes]
+			neighbors.Push(n, float32(hammingDistance(subQuery, subData)))
+		}
+
+		smallestK := neighbors.SmallestK()
+		results[q] = make([]int, len(smallestK))
+		distances[q] = make([]int, len(smallestK))
+		for i, item := range smallestK {
+			results[q][i] = item.index
+			distances[q][i] = int(item.value)
+		}
+	}
+
+	return results, distances, nil
+}
+
+func (index *BinaryFlatIndex) NumVectors() int {
+	return len(index.state.Data) / index.state.NumBytes
+}
+
+func (index *BinaryFlatIndex) Describe() string {
+	return "BFlat"
+}
+
+func (index *BinaryFlatIndex) Info() IndexInfo {
+	return IndexInfo{
+		IndexType:   IndexTypeBinaryFlat,
+		CodeType1:   CodeTypeNameNone,
+		CodeType2:   CodeTypeNameNone,
+		Spec:        index.Describe(),
+		NumFeatures: index.state.NumBits,
+		NumVectors:  index.NumVectors(),
+		IsTrained:   true,
+		Memory: MemoryUsage{
+			Data: sizeOf[uint8](cap(index.state.Data)),
+		}.withTotal(),
+	}
+}
+
+func (index *BinaryFlatIndex) Save(enc *gob.Encoder) error {
+	meta := MetaData{
+		IndexType: IndexTypeBinaryFlat,
+		CodeType1: CodeTypeNameNone,
+		CodeType2: CodeTypeNameNone,
+	}
+	err := enc.Encode(meta)
+	if err != nil {
+		return err
+	}
+	return index.encode(enc)
+}
+
+func (index *BinaryFlatIndex) encode(enc *gob.Encoder) error {
+	return enc.Encode(index.state)
+}
+
+func (index *BinaryFlatIndex) decode(dec *gob.Decoder) error {
+	index.state = &BinaryFlatIndexState{}
+	return dec.Decode(index.state)
+}
+
+// PackBinaryVectors converts vectors packed into uint64 words into the byte
+// layout accepted by binary indexes.
+func PackBinaryVectors(words []uint64) []uint8 {
+	data := make([]uint8, len(words)*8)
+	for i, word := range words {
+		binary.LittleEndian.PutUint64(data[i*8:], word)
+	}
+	return data
+}
+
+func hammingDistance(x, y []uint8) int {
+	distance := 0
+	i := 0
+	for ; i+8 <= len(x); i += 8 {
+		distance += bits.OnesCount64(binary.LittleEndian.Uint64(x[i:]) ^ binary.LittleEndian.Uint64(y[i:]))
+	}
+	for ; i < len(x); i++ {
+		distance += bits.OnesCount8(x[i] ^ y[i])
+	}
+	return distance
+}
@@ -0,0 +1,77 @@
+package vanadium_index
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+)
+
+func TestBinaryFlatIndexSearch(t *testing.T) {
+	index, _ := newBinaryFlatIndex(16)
+	index.Add([]uint8{0b00000000, 0b00000000, 0b11111111, 0b00000000})
+	index.Add([]uint8{0b11111111, 0b11111111})
+
+	results, distances, err := index.Search([]uint8{
+		0b00000001, 0b00000000,
+		0b11111111, 0b00000000,
+		0b11111111, 0b11111111,
+	}, 2)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	expectedResults := [][]int{{0, 1}, {1, 0}, {2, 1}}
+	expectedDistances := [][]int{{1, 7}, {0, 8}, {0, 8}}
+	for i := range results {
+		for j := range results[i] {
+			if results[i][j] != expectedResults[i][j] {
+				t.Fatalf("results[%d][%d] = %d, expected %d", i, j, results[i][j], expectedResults[i][j])
+			}
+			if distances[i][j] != expectedDistances[i][j] {
+				t.Fatalf("distances[%d][%d] = %d, expected %d", i, j, distances[i][j], expectedDistances[i][j])
+			}
+		}
+	}
+}
+
+func TestBinaryFlatIndexSaveLoad(t *testing.T) {
+	index, _ := newBinaryFlatIndex(16)
+	index.Add([]uint8{1, 2, 3, 4})
+	index.Add([]uint8{5, 6})
+
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	err := index.Save(enc)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	dec := gob.NewDecoder(&buf)
+	binaryIndex, err := LoadBinaryIndex(dec)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	index2, ok := binaryIndex.(*BinaryFlatIndex)
+	if !ok {
+		t.Fatalf("loaded index is not a BinaryFlatIndex")
+	}
+
+	if index2.state.NumBits != index.state.NumBits {
+		t.Fatalf("numBits mismatch: %d != %d", index2.state.NumBits, index.state.NumBits)
+	}
+
+	if !bytes.Equal(index2.state.Data, index.state.Data) {
+		t.Fatalf("data mismatch: %v != %v", index2.state.Data, index.state.Data)
+	}
+}
+
+func TestHammingDistance(t *testing.T) {
+	x := PackBinaryVectors([]uint64{0xFFFFFFFFFFFFFFFF, 0})
+	y := PackBinaryVectors([]uint64{0, 0})
+	x = append(x, 0b00000111)
+	y = append(y, 0b00000001)
+	if d := hammingDistance(x, y); d != 66 {
+		t.Fatalf("hammingDistance = %d, expected 66", d)
+	}
+}
@@ -0,0 +1,280 @@
+package vanadium_index
+
+import (
+	"encoding/gob"
+	"fmt"
+	"reflect"
+)
+
+type BinaryInvertedFileIndex[T CodeType] struct {
+	state   *BinaryInvertedFileIndexState[T]
+	cluster *kMajority
+	indexes []*BinaryFlatIndex
+}
+
+type BinaryInvertedFileIndexState[T CodeType] struct {
+	NumBits     int
+	NumBytes    int
+	NumClusters T
+	IsTrained   bool
+	Config      *BinaryInvertedFileIndexConfig
+	Mapping     [][]int
+	// IsMappingDelta reports that Mapping was saved delta-encoded.
+	IsMappingDelta bool
+}
+
+type BinaryInvertedFileIndexConfig struct {
+	MaxIterations int
+	Seed          uint64
+	IsSeeded      bool
+}
+
+func newBinaryInvertedFileIndex[T CodeType](
+	numBits int,
+	numClusters T,
+	opts ...BinaryInvertedFileIndexOption,
+) (*BinaryInvertedFileIndex[T], error) {
+	if numBits <= 0 || numBits%8 != 0 {
+		return nil, ErrInvalidNumBits
+	}
+	numBytes := numBits / 8
+
+	index := &BinaryInvertedFileIndex[T]{
+		state: &BinaryInvertedFileIndexState[T]{
+			NumBits:     numBits,
+			NumBytes:    numBytes,
+			NumClusters: numClusters,
+			IsTrained:   false,
+			// Default values
+			Config: &BinaryInvertedFileIndexConfig{
+				MaxIterations: 100,
+			},
+			Mapping: make([][]int, numClusters),
+		},
+	}
+	for _, opt := range opts {
+		err := opt(index.state.Config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cluster, err := newKMajority(int(numClusters), numBytes)
+	if err != nil {
+		return nil, err
+	}
+	index.cluster = cluster
+
+	index.indexes = make([]*BinaryFlatIndex, numClusters)
+	for c := range int(numClusters) {
+		subIndex, err := newBinaryFlatIndex(numBits)
+		if err != nil {
+			return nil, err
+		}
+		index.indexes[c] = subIndex
+	}
+	return index, nil
+}
+
+func loadBinaryInvertedFileIndex[T CodeType](dec *gob.Decoder) (*BinaryInvertedFileIndex[T], error) {
+	index := &BinaryInvertedFileIndex[T]{}
+	err := index.decode(dec)
+	if err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func (index *BinaryInvertedFileIndex[T]) Train(data []uint8) error {
+	if len(data) == 0 {
+		return newIndexError(OpTrain, IndexTypeBinaryIVF, ErrEmptyData)
+	}
+
+	if len(data)%index.state.NumBytes != 0 {
+		return newIndexError(OpTrain, IndexTypeBinaryIVF, ErrInvalidDataLength, "dataLength", len(data), "numBytes", index.state.NumBytes)
+	}
+
+	if numVectors := len(data) / index.state.NumBytes; numVectors < int(index.state.NumClusters) {
+		return newIndexError(
+			OpTrain, IndexTypeBinaryIVF, ErrInsufficientTrainingData,
+			"required", int(index.state.NumClusters), "actual", numVectors,
+		)
+	}
+
+	rng := newRand(index.state.Config.Seed, index.state.Config.IsSeeded)
+	_, err := index.cluster.Train(data, index.state.Config.MaxIterations, rng)
+	if err != nil {
+		return err
+	}
+
+	index.state.IsTrained = true
+	return nil
+}
+
+func (index *BinaryInvertedFileIndex[T]) Add(data []uint8) error {
+	if len(data) == 0 {
+		return newIndexError(OpAdd, IndexTypeBinaryIVF, ErrEmptyData)
+	}
+
+	if len(data)%index.state.NumBytes != 0 {
+		return newIndexError(OpAdd, IndexTypeBinaryIVF, ErrInvalidDataLength, "dataLength", len(data), "numBytes", index.state.NumBytes)
+	}
+
+	if !index.state.IsTrained {
+		return newIndexError(OpAdd, IndexTypeBinaryIVF, ErrNotTrained)
+	}
+
+	ivfRow := index.NumVectors()
+	return index.cluster.Predict(data, func(row, minCol, minDist int) error {
+		rowData := data[row*index.state.NumBytes : (row+1)*index.state.NumBytes]
+		index.state.Mapping[minCol] = append(index.state.Mapping[minCol], ivfRow+row)
+		return index.indexes[minCol].Add(rowData)
+	})
+}
+
+func (index *BinaryInvertedFileIndex[T]) Search(query []uint8, k int) ([][]int, [][]int, error) {
+	if k <= 0 {
+		return nil, nil, newIndexError(OpSearch, IndexTypeBinaryIVF, ErrInvalidK, "k", k)
+	}
+
+	if len(query) == 0 {
+		return nil, nil, newIndexError(OpSearch, IndexTypeBinaryIVF, ErrEmptyData)
+	}
+
+	if len(query)%index.state.NumBytes != 0 {
+		return nil, nil, newIndexError(OpSearch, IndexTypeBinaryIVF, ErrInvalidDataLength, "dataLength", len(query), "numBytes", index.state.NumBytes)
+	}
+
+	if !index.state.IsTrained {
+		return nil, nil, newIndexError(OpSearch, IndexTypeBinaryIVF, ErrNotTrained)
+	}
+
+	numQueries := len(query) / index.state.NumBytes
+	results := make([][]int, numQueries)
+	distances := make([][]int, numQueries)
+	err := index.cluster.Predict(query, func(row, minCol, minDist int) error {
+		rowQuery := query[row*index.state.NumBytes : (row+1)*index.state.NumBytes]
+		result, distance, err := index.indexes[minCol].Search(rowQuery, k)
+		if err != nil {
+			return err
+		}
+		results[row] = make([]int, len(result[0]))
+		distances[row] = make([]int, len(distance[0]))
+		for i, r := range result[0] {
+			results[row][i] = index.state.Mapping[minCol][r]
+			distances[row][i] = distance[0][i]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return results, distances, nil
+}
+
+func (index *BinaryInvertedFileIndex[T]) NumVectors() int {
+	numVectors := 0
+	for _, index := range index.indexes {
+		numVectors += index.NumVectors()
+	}
+	return numVectors
+}
+
+func (index *BinaryInvertedFileIndex[T]) Describe() string {
+	return fmt.Sprintf("BIVF%d", index.state.NumClusters)
+}
+
+func (index *BinaryInvertedFileIndex[T]) Info() IndexInfo {
+	memory := MemoryUsage{
+		Codebooks: sizeOf[uint8](int(index.state.NumClusters) * index.state.NumBytes),
+		Mapping:   mappingSize(index.state.Mapping),
+	}.withTotal()
+
+	sizes := make([]int, len(index.state.Mapping))
+	for c, ids := range index.state.Mapping {
+		sizes[c] = len(ids)
+	}
+	for _, subIndex := range index.indexes {
+		memory = memory.add(subIndex.Info().Memory)
+	}
+	listSizes := newListSizeStats(sizes)
+	return IndexInfo{
+		IndexType:         IndexTypeBinaryIVF,
+		CodeType1:         codeTypeName[T](),
+		CodeType2:         CodeTypeNameNone,
+		Spec:              index.Describe(),
+		NumFeatures:       index.state.NumBits,
+		NumVectors:        index.NumVectors(),
+		IsTrained:         index.state.IsTrained,
+		NumClusters:       int(index.state.NumClusters),
+		MaxIterations:     index.state.Config.MaxIterations,
+		Memory:            memory,
+		ListSizes:         &listSizes,
+		ListSizeHistogram: listSizeHistogram(sizes),
+	}
+}
+
+func (index *BinaryInvertedFileIndex[T]) Save(enc *gob.Encoder) error {
+	var t T
+	meta := MetaData{
+		IndexType: IndexTypeBinaryIVF,
+		CodeType1: CodeTypeName(reflect.TypeOf(t).String()),
+		CodeType2: CodeTypeNameNone,
+	}
+	err := enc.Encode(meta)
+	if err != nil {
+		return err
+	}
+	return index.encode(enc)
+}
+
+func (index *BinaryInvertedFileIndex[T]) encode(enc *gob.Encoder) error {
+	state := *index.state
+	state.Mapping = deltaEncodeMapping(index.state.Mapping)
+	state.IsMappingDelta = true
+	err := enc.Encode(&state)
+	if err != nil {
+		return err
+	}
+	err = index.cluster.Encode(enc)
+	if err != nil {
+		return err
+	}
+	for _, index := range index.indexes {
+		err = index.encode(enc)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (index *BinaryInvertedFileIndex[T]) decode(dec *gob.Decoder) error {
+	index.state = &BinaryInvertedFileIndexState[T]{
+		Config: &BinaryInvertedFileIndexConfig{},
+	}
+	err := dec.Decode(index.state)
+	if err != nil {
+		return err
+	}
+	if index.state.IsMappingDelta {
+		deltaDecodeMapping(index.state.Mapping)
+		index.state.IsMappingDelta = false
+	}
+
+	cluster, err := loadKMajority(dec)
+	if err != nil {
+		return err
+	}
+	index.cluster = cluster
+
+	index.indexes = make([]*BinaryFlatIndex, index.state.NumClusters)
+	for i := range int(index.state.NumClusters) {
+		index.indexes[i], err = loadBinaryFlatIndex(dec)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package vanadium_index
+
+type BinaryInvertedFileIndexOption func(*BinaryInvertedFileIndexConfig) error
+
+func WithBinaryIVFMaxIterations(maxIterations int) BinaryInvertedFileIndexOption {
+	return func(config *BinaryInvertedFileIndexConfig) error {
+		if maxIterations <= 0 {
+			return ErrInvalidNumIterations
+		}
+		config.MaxIterations = maxIterations
+		return nil
+	}
+}
+
+// WithBinaryIVFSeed makes training reproducible. The seed drives the
+// initialization of the k-majority centroids.
+func WithBinaryIVFSeed(seed uint64) BinaryInvertedFileIndexOption {
+	return func(config *BinaryInvertedFileIndexConfig) error {
+		config.Seed = seed
+		config.IsSeeded = true
+		return nil
+	}
+}
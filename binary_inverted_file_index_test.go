@@ -0,0 +1,141 @@
+package vanadium_index
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+)
+
+func TestBinaryInvertedFileIndex(t *testing.T) {
+	index, err := newBinaryInvertedFileIndex(16, uint8(2), WithBinaryIVFMaxIterations(10))
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	data := []uint8{
+		0b00000000, 0b00000000,
+		0b00000001, 0b00000000,
+		0b11111111, 0b11111111,
+		0b11111110, 0b11111111,
+	}
+
+	err = index.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+
+	for i := 0; i < len(data); i += 2 {
+		err = index.Add(data[i : i+2])
+		if err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+	}
+
+	results, distances, err := index.Search(data, 1)
+	if err != nil {
+		t.Fatalf("Failed to search index: %v", err)
+	}
+
+	for i, result := range results {
+		if result[0] != i {
+			t.Fatalf("result[%d] = %d, expected %d", i, result[0], i)
+		}
+	}
+
+	for i, distance := range distances {
+		if distance[0] != 0 {
+			t.Fatalf("distance[%d] = %d, expected 0", i, distance[0])
+		}
+	}
+}
+
+func TestBinaryInvertedFileIndexWithSeed(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]uint8, 200*4)
+	for i := range data {
+		data[i] = uint8(rng.Uint32())
+	}
+
+	centroids := make([][][]uint8, 2)
+	for i := range centroids {
+		index, err := newBinaryInvertedFileIndex(32, uint8(8), WithBinaryIVFSeed(42))
+		if err != nil {
+			t.Fatalf("Failed to create index: %v", err)
+		}
+		err = index.Train(data)
+		if err != nil {
+			t.Fatalf("Failed to train index: %v", err)
+		}
+		centroids[i] = index.cluster.state.Centroids
+	}
+	if !reflect.DeepEqual(centroids[0], centroids[1]) {
+		t.Fatalf("centroid mismatch: %v != %v", centroids[0], centroids[1])
+	}
+}
+
+func TestBinaryInvertedFileIndexSaveLoad(t *testing.T) {
+	index, err := newBinaryInvertedFileIndex(16, uint8(2), WithBinaryIVFMaxIterations(10))
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	data := []uint8{
+		0b00000000, 0b00000000,
+		0b00000001, 0b00000000,
+		0b11111111, 0b11111111,
+		0b11111110, 0b11111111,
+	}
+
+	err = index.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+
+	err = index.Add(data)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	err = index.Save(enc)
+	if err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+
+	dec := gob.NewDecoder(&buf)
+	binaryIndex, err := LoadBinaryIndex(dec)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	index2, ok := binaryIndex.(*BinaryInvertedFileIndex[uint8])
+	if !ok {
+		t.Fatalf("loaded index is not a BinaryInvertedFileIndex")
+	}
+
+	if index2.state.NumClusters != index.state.NumClusters {
+		t.Fatalf("index2.state.NumClusters = %d, expected %d", index2.state.NumClusters, index.state.NumClusters)
+	}
+
+	if index2.state.Config.MaxIterations != index.state.Config.MaxIterations {
+		t.Fatalf("index2.state.Config.MaxIterations = %d, expected %d", index2.state.Config.MaxIterations, index.state.Config.MaxIterations)
+	}
+
+	for i := range index.state.Mapping {
+		for j := range index.state.Mapping[i] {
+			if index2.state.Mapping[i][j] != index.state.Mapping[i][j] {
+				t.Fatalf("index2.state.Mapping[%d][%d] = %d, expected %d", i, j, index2.state.Mapping[i][j], index.state.Mapping[i][j])
+			}
+		}
+	}
+
+	for i := range index.cluster.state.Centroids {
+		if !bytes.Equal(index2.cluster.state.Centroids[i], index.cluster.state.Centroids[i]) {
+			t.Fatalf("centroids mismatch: %v != %v", index2.cluster.state.Centroids[i], index.cluster.state.Centroids[i])
+		}
+	}
+
+	if index2.NumVectors() != index.NumVectors() {
+		t.Fatalf("index2.NumVectors() = %d, expected %d", index2.NumVectors(), index.NumVectors())
+	}
+}
@@ -0,0 +1,148 @@
+package vanadium_index
+
+import (
+	"encoding/gob"
+	"math/rand/v2"
+)
+
+// kMajority clusters binary vectors under Hamming distance. Centroids are
+// updated by a bitwise majority vote of the assigned vectors.
+type kMajority struct {
+	state *kMajorityState
+}
+
+type kMajorityState struct {
+	NumClusters int
+	NumBytes    int
+	Centroids   [][]uint8
+}
+
+func newKMajority(numClusters, numBytes int) (*kMajority, error) {
+	if numClusters <= 0 {
+		return nil, ErrInvalidNumClusters
+	}
+	if numBytes <= 0 {
+		return nil, ErrInvalidNumBits
+	}
+	return &kMajority{
+		state: &kMajorityState{
+			NumClusters: numClusters,
+			NumBytes:    numBytes,
+			Centroids:   make([][]uint8, numClusters),
+		},
+	}, nil
+}
+
+func loadKMajority(dec *gob.Decoder) (*kMajority, error) {
+	km := &kMajority{state: &kMajorityState{}}
+	err := dec.Decode(km.state)
+	if err != nil {
+		return nil, err
+	}
+	return km, nil
+}
+
+// Train runs k-majority on data. The initial centroids are drawn from rng.
+func (km *kMajority) Train(data []uint8, maxIterations int, rng *rand.Rand) (int, error) {
+	if len(data) == 0 {
+		return 0, ErrEmptyData
+	}
+	if len(data)%km.state.NumBytes != 0 {
+		return 0, ErrInvalidDataLength
+	}
+	N := len(data) / km.state.NumBytes
+	if km.state.NumClusters > N {
+		return 0, ErrInsufficientTrainingData
+	}
+
+	for i, idx := range rng.Perm(N)[:km.state.NumClusters] {
+		centroid := make([]uint8, km.state.NumBytes)
+		copy(centroid, data[idx*km.state.NumBytes:(idx+1)*km.state.NumBytes])
+		km.state.Centroids[i] = centroid
+	}
+
+	numBits := km.state.NumBytes * 8
+	assignments := make([]int, N)
+	for n := range N {
+		assignments[n] = -1
+	}
+
+	numIter := 0
+	for i := range maxIterations {
+		numIter = i
+		changed := false
+		counts := make([]int, km.state.NumClusters)
+		votes := make([][]int, km.state.NumClusters)
+		for c := range km.state.NumClusters {
+			votes[c] = make([]int, numBits)
+		}
+
+		err := km.Predict(data, func(row, minCol, minDist int) error {
+			if assignments[row] != minCol {
+				assignments[row] = minCol
+				changed = true
+			}
+			counts[minCol]++
+			x := data[row*km.state.NumBytes : (row+1)*km.state.NumBytes]
+			for b := range numBits {
+				if x[b/8]&(1<<(b%8)) != 0 {
+					votes[minCol][b]++
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		if !changed {
+			break
+		}
+
+		for c := range km.state.NumClusters {
+			if counts[c] == 0 {
+				continue
+			}
+			centroid := make([]uint8, km.state.NumBytes)
+			for b := range numBits {
+				if 2*votes[c][b] > counts[c] {
+					centroid[b/8] |= 1 << (b % 8)
+				}
+			}
+			km.state.Centroids[c] = centroid
+		}
+	}
+
+	return numIter, nil
+}
+
+func (km *kMajority) Predict(data []uint8, fn func(row, minCol, minDist int) error) error {
+	if len(data) == 0 {
+		return ErrEmptyData
+	}
+	if len(data)%km.state.NumBytes != 0 {
+		return ErrInvalidDataLength
+	}
+
+	N := len(data) / km.state.NumBytes
+	for n := range N {
+		x := data[n*km.state.NumBytes : (n+1)*km.state.NumBytes]
+		minCol := 0
+		minDist := hammingDistance(x, km.state.Centroids[0])
+		for c := 1; c < km.state.NumClusters; c++ {
+			dist := hammingDistance(x, km.state.Centroids[c])
+			if dist < minDist {
+				minDist = dist
+				minCol = c
+			}
+		}
+		err := fn(n, minCol, minDist)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (km *kMajority) Encode(enc *gob.Encoder) error {
+	return enc.Encode(km.state)
+}
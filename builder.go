@@ -82,6 +82,49 @@ func AsIVFPQ(numClusters int, numSubspaces int, numClustersPerSubspace int, opts
 	}
 }
 
+func AsRefine(base IndexBuilder, kFactor int, opts ...RefineIndexOption) IndexBuilder {
+	return func(config *IndexConfig) (ANNIndex, error) {
+		index, err := base(config)
+		if err != nil {
+			return nil, err
+		}
+		return newRefineIndex(config.NumFeatures, index, kFactor, opts...)
+	}
+}
+
+// AsSharded spreads vectors over numShards indexes built by shard.
+func AsSharded(numShards int, shard IndexBuilder, opts ...ShardedIndexOption) IndexBuilder {
+	return func(config *IndexConfig) (ANNIndex, error) {
+		if numShards <= 0 {
+			return nil, ErrInvalidNumShards
+		}
+		shards := make([]ANNIndex, numShards)
+		for s := range numShards {
+			index, err := shard(config)
+			if err != nil {
+				return nil, err
+			}
+			shards[s] = index
+		}
+		return newShardedIndex(config.NumFeatures, shards, opts...)
+	}
+}
+
+// FromModel builds empty indexes that share the trained model of model, for
+// example to give every shard of AsSharded the same pre-trained model.
+func FromModel(model ANNIndex) IndexBuilder {
+	return func(config *IndexConfig) (ANNIndex, error) {
+		info := model.Info()
+		if info.NumFeatures != config.NumFeatures {
+			return nil, newIndexError(
+				OpNew, info.IndexType, ErrIncompatibleIndex,
+				"numFeatures", config.NumFeatures, "modelNumFeatures", info.NumFeatures,
+			)
+		}
+		return NewIndexFromModel(model)
+	}
+}
+
 type IndexConfig struct {
 	NumFeatures int
 }
@@ -92,3 +135,37 @@ func NewIndex(numFeatures int, builder IndexBuilder) (ANNIndex, error) {
 	}
 	return builder(config)
 }
+
+type BinaryIndexBuilder func(*IndexConfig) (BinaryANNIndex, error)
+
+func AsBinaryFlat() BinaryIndexBuilder {
+	return func(config *IndexConfig) (BinaryANNIndex, error) {
+		return newBinaryFlatIndex(config.NumFeatures)
+	}
+}
+
+func AsBinaryIVF(numClusters int, opts ...BinaryInvertedFileIndexOption) BinaryIndexBuilder {
+	return func(config *IndexConfig) (BinaryANNIndex, error) {
+		if numClusters <= 0 || numClusters > math.MaxUint32 {
+			return nil, ErrInvalidNumClusters
+		}
+
+		switch {
+		case numClusters < math.MaxUint8:
+			return newBinaryInvertedFileIndex(config.NumFeatures, uint8(numClusters), opts...)
+		case numClusters < math.MaxUint16:
+			return newBinaryInvertedFileIndex(config.NumFeatures, uint16(numClusters), opts...)
+		default:
+			return newBinaryInvertedFileIndex(config.NumFeatures, uint32(numClusters), opts...)
+		}
+	}
+}
+
+// NewBinaryIndex creates a binary index over vectors of numBits bits, packed
+// into numBits/8 bytes each.
+func NewBinaryIndex(numBits int, builder BinaryIndexBuilder) (BinaryANNIndex, error) {
+	config := &IndexConfig{
+		NumFeatures: numBits,
+	}
+	return builder(config)
+}
@@ -0,0 +1,106 @@
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+import (
+	"errors"
+
+	vanadium "github.com/monochromegane/vanadium-index"
+)
+
+// Error codes returned by the exported functions. 0 means success and 1 is
+// kept for errors that do not match any of the sentinels below, so callers
+// checking for a non-zero result keep working.
+const (
+	errCodeOK = iota
+	errCodeUnknown
+	errCodeInvalidDataLength
+	errCodeInvalidNumFeatures
+	errCodeEmptyData
+	errCodeInvalidK
+	errCodeInvalidNumSubspaces
+	errCodeInvalidNumClusters
+	errCodeInvalidNumIterations
+	errCodeInvalidTol
+	errCodeInvalidPQOptions
+	errCodeNotTrained
+	errCodeInsufficientTrainingData
+	errCodeInvalidKFactor
+	errCodeInvalidNumBits
+	errCodeInvalidHNSWParameters
+	errCodeInvalidListSizeRatio
+	errCodeInvalidSampleSize
+	errCodeInvalidInitMethod
+	errCodeInvalidBatchSize
+	errCodeUnknownIndexType
+	errCodeUnknownCodeType
+	errCodeInvalidIndexSpec
+	errCodeIncompatibleIndex
+	errCodeInvalidNumShards
+	errCodeInvalidManifest
+	errCodeUnsupportedFAISSIndex
+	errCodeInvalidFAISSIndex
+	errCodeInvalidCompressionLevel
+	errCodeInvalidCode
+	errCodeNoCodes
+	errCodeIndexNotEmpty
+	errCodeInvalidCheckpointInterval
+	errCodeCorruptLog
+	errCodeCheckpointFailed
+)
+
+var errorCodes = []struct {
+	err  error
+	code C.int
+}{
+	{vanadium.ErrInvalidDataLength, errCodeInvalidDataLength},
+	{vanadium.ErrInvalidNumFeatures, errCodeInvalidNumFeatures},
+	{vanadium.ErrEmptyData, errCodeEmptyData},
+	{vanadium.ErrInvalidK, errCodeInvalidK},
+	{vanadium.ErrInvalidNumSubspaces, errCodeInvalidNumSubspaces},
+	{vanadium.ErrInvalidNumClusters, errCodeInvalidNumClusters},
+	{vanadium.ErrInvalidNumIterations, errCodeInvalidNumIterations},
+	{vanadium.ErrInvalidTol, errCodeInvalidTol},
+	{vanadium.ErrInvalidPQOptions, errCodeInvalidPQOptions},
+	{vanadium.ErrNotTrained, errCodeNotTrained},
+	{vanadium.ErrInsufficientTrainingData, errCodeInsufficientTrainingData},
+	{vanadium.ErrInvalidKFactor, errCodeInvalidKFactor},
+	{vanadium.ErrInvalidNumBits, errCodeInvalidNumBits},
+	{vanadium.ErrInvalidHNSWParameters, errCodeInvalidHNSWParameters},
+	{vanadium.ErrInvalidListSizeRatio, errCodeInvalidListSizeRatio},
+	{vanadium.ErrInvalidSampleSize, errCodeInvalidSampleSize},
+	{vanadium.ErrInvalidInitMethod, errCodeInvalidInitMethod},
+	{vanadium.ErrInvalidBatchSize, errCodeInvalidBatchSize},
+	{vanadium.ErrUnknownIndexType, errCodeUnknownIndexType},
+	{vanadium.ErrUnknownCodeType, errCodeUnknownCodeType},
+	{vanadium.ErrInvalidIndexSpec, errCodeInvalidIndexSpec},
+	{vanadium.ErrIncompatibleIndex, errCodeIncompatibleIndex},
+	{vanadium.ErrInvalidNumShards, errCodeInvalidNumShards},
+	{vanadium.ErrInvalidManifest, errCodeInvalidManifest},
+	{vanadium.ErrUnsupportedFAISSIndex, errCodeUnsupportedFAISSIndex},
+	{vanadium.ErrInvalidFAISSIndex, errCodeInvalidFAISSIndex},
+	{vanadium.ErrInvalidCompressionLevel, errCodeInvalidCompressionLevel},
+	{vanadium.ErrInvalidCode, errCodeInvalidCode},
+	{vanadium.ErrNoCodes, errCodeNoCodes},
+	{vanadium.ErrIndexNotEmpty, errCodeIndexNotEmpty},
+	{vanadium.ErrInvalidCheckpointInterval, errCodeInvalidCheckpointInterval},
+	{vanadium.ErrCorruptLog, errCodeCorruptLog},
+	{vanadium.ErrCheckpointFailed, errCodeCheckpointFailed},
+}
+
+func errorCode(err error) C.int {
+	for _, e := range errorCodes {
+		if errors.Is(err, e.err) {
+			return e.code
+		}
+	}
+	return errCodeUnknown
+}
+
+// setError stores the message of err in errMsg and returns its error code.
+func setError(errMsg **C.char, err error) C.int {
+	*errMsg = C.CString(err.Error())
+	return errorCode(err)
+}
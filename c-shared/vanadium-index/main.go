@@ -6,7 +6,10 @@ package main
 */
 import "C"
 import (
+	"bufio"
+	"bytes"
 	"encoding/gob"
+	"encoding/json"
 	"os"
 	"runtime/cgo"
 	"unsafe"
@@ -18,8 +21,7 @@ import (
 func NewFlatIndex(handle *C.ulong, errMsg **C.char, numFeatures C.int) C.int {
 	annIndex, err := vanadium.NewIndex(int(numFeatures), vanadium.AsFlat())
 	if err != nil {
-		*errMsg = C.CString(err.Error())
-		return 1
+		return setError(errMsg, err)
 	}
 	h := cgo.NewHandle(annIndex)
 	*handle = C.ulong(h)
@@ -45,8 +47,7 @@ func NewPQIndex(handle *C.ulong, errMsg **C.char, numFeatures C.int, numSubspace
 		),
 	)
 	if err != nil {
-		*errMsg = C.CString(err.Error())
-		return 1
+		return setError(errMsg, err)
 	}
 	h := cgo.NewHandle(annIndex)
 	*handle = C.ulong(h)
@@ -65,8 +66,7 @@ func NewIVFFlatIndex(handle *C.ulong, errMsg **C.char, numFeatures C.int, numClu
 	}
 	annIndex, err := vanadium.NewIndex(int(numFeatures), vanadium.AsIVFFlat(int(numClusters), opts...))
 	if err != nil {
-		*errMsg = C.CString(err.Error())
-		return 1
+		return setError(errMsg, err)
 	}
 	h := cgo.NewHandle(annIndex)
 	*handle = C.ulong(h)
@@ -100,8 +100,7 @@ func NewIVFPQIndex(handle *C.ulong, errMsg **C.char, numFeatures C.int, numClust
 		),
 	)
 	if err != nil {
-		*errMsg = C.CString(err.Error())
-		return 1
+		return setError(errMsg, err)
 	}
 	h := cgo.NewHandle(annIndex)
 	*handle = C.ulong(h)
@@ -109,6 +108,58 @@ func NewIVFPQIndex(handle *C.ulong, errMsg **C.char, numFeatures C.int, numClust
 	return 0
 }
 
+//export NewBinaryFlatIndex
+func NewBinaryFlatIndex(handle *C.ulong, errMsg **C.char, numBits C.int) C.int {
+	binaryIndex, err := vanadium.NewBinaryIndex(int(numBits), vanadium.AsBinaryFlat())
+	if err != nil {
+		return setError(errMsg, err)
+	}
+	h := cgo.NewHandle(binaryIndex)
+	*handle = C.ulong(h)
+	*errMsg = nil
+	return 0
+}
+
+//export NewBinaryIVFIndex
+func NewBinaryIVFIndex(handle *C.ulong, errMsg **C.char, numBits C.int, numClusters C.int, maxIterations C.int) C.int {
+	opts := []vanadium.BinaryInvertedFileIndexOption{}
+	if maxIterations > 0 {
+		opts = append(opts, vanadium.WithBinaryIVFMaxIterations(int(maxIterations)))
+	}
+	binaryIndex, err := vanadium.NewBinaryIndex(int(numBits), vanadium.AsBinaryIVF(int(numClusters), opts...))
+	if err != nil {
+		return setError(errMsg, err)
+	}
+	h := cgo.NewHandle(binaryIndex)
+	*handle = C.ulong(h)
+	*errMsg = nil
+	return 0
+}
+
+//export NewIndexFromSpec
+func NewIndexFromSpec(handle *C.ulong, errMsg **C.char, numFeatures C.int, spec *C.char) C.int {
+	annIndex, err := vanadium.NewIndexFromSpec(int(numFeatures), C.GoString(spec))
+	if err != nil {
+		return setError(errMsg, err)
+	}
+	h := cgo.NewHandle(annIndex)
+	*handle = C.ulong(h)
+	*errMsg = nil
+	return 0
+}
+
+//export NewBinaryIndexFromSpec
+func NewBinaryIndexFromSpec(handle *C.ulong, errMsg **C.char, numBits C.int, spec *C.char) C.int {
+	binaryIndex, err := vanadium.NewBinaryIndexFromSpec(int(numBits), C.GoString(spec))
+	if err != nil {
+		return setError(errMsg, err)
+	}
+	h := cgo.NewHandle(binaryIndex)
+	*handle = C.ulong(h)
+	*errMsg = nil
+	return 0
+}
+
 //export FreeIndex
 func FreeIndex(handle C.ulong) {
 	h := cgo.Handle(handle)
@@ -126,8 +177,7 @@ func Train(handle C.ulong, errMsg **C.char, data *C.float, dataLength C.int) C.i
 	dataSlice := *(*[]float32)(unsafe.Pointer(&slice))
 	annIndex := cgo.Handle(handle).Value().(vanadium.ANNIndex)
 	if err := annIndex.Train(dataSlice); err != nil {
-		*errMsg = C.CString(err.Error())
-		return 1
+		return setError(errMsg, err)
 	}
 	*errMsg = nil
 	return 0
@@ -142,19 +192,55 @@ func Add(handle C.ulong, errMsg **C.char, keepData C.bool, data *C.float, dataLe
 		copiedData := make([]float32, dataLength)
 		copy(copiedData, dataSlice)
 		if err := annIndex.Add(copiedData); err != nil {
-			*errMsg = C.CString(err.Error())
-			return 1
+			return setError(errMsg, err)
 		}
 	} else {
 		if err := annIndex.Add(dataSlice); err != nil {
-			*errMsg = C.CString(err.Error())
-			return 1
+			return setError(errMsg, err)
 		}
 	}
 	*errMsg = nil
 	return 0
 }
 
+// AddFromFile adds vectors stored as little-endian float32 values in path,
+// reading batchSize vectors at a time.
+//
+//export AddFromFile
+func AddFromFile(handle C.ulong, errMsg **C.char, path *C.char, batchSize C.int) C.int {
+	annIndex := cgo.Handle(handle).Value().(vanadium.ANNIndex)
+	file, err := os.Open(C.GoString(path))
+	if err != nil {
+		return setError(errMsg, err)
+	}
+	defer file.Close()
+
+	opts := []vanadium.AddFromOption{}
+	if batchSize > 0 {
+		opts = append(opts, vanadium.WithAddFromBatchSize(int(batchSize)))
+	}
+	_, err = vanadium.AddFrom(annIndex, vanadium.NewFloat32Reader(bufio.NewReader(file)), opts...)
+	if err != nil {
+		return setError(errMsg, err)
+	}
+	*errMsg = nil
+	return 0
+}
+
+// Merge appends the vectors of the index behind otherHandle to the index
+// behind handle. The other index is left unchanged.
+//
+//export Merge
+func Merge(handle C.ulong, errMsg **C.char, otherHandle C.ulong) C.int {
+	annIndex := cgo.Handle(handle).Value().(vanadium.ANNIndex)
+	otherIndex := cgo.Handle(otherHandle).Value().(vanadium.ANNIndex)
+	if err := annIndex.Merge(otherIndex); err != nil {
+		return setError(errMsg, err)
+	}
+	*errMsg = nil
+	return 0
+}
+
 //export Search
 func Search(handle C.ulong, errMsg **C.char, query *C.float, queryLength C.int, k C.int,
 	outIndices **C.int, outDistances **C.float, outOffsets *C.int, outLengths *C.int) C.int {
@@ -164,8 +250,7 @@ func Search(handle C.ulong, errMsg **C.char, query *C.float, queryLength C.int,
 	querySlice := *(*[]float32)(unsafe.Pointer(&slice))
 	resultIndices, resultDistances, err := annIndex.Search(querySlice, int(k))
 	if err != nil {
-		*errMsg = C.CString(err.Error())
-		return 1
+		return setError(errMsg, err)
 	}
 
 	total := 0
@@ -196,25 +281,148 @@ func Search(handle C.ulong, errMsg **C.char, query *C.float, queryLength C.int,
 	return 0
 }
 
+//export TrainBinary
+func TrainBinary(handle C.ulong, errMsg **C.char, data *C.uchar, dataLength C.int) C.int {
+	slice := unsafe.Slice(data, dataLength)
+	dataSlice := *(*[]uint8)(unsafe.Pointer(&slice))
+	binaryIndex := cgo.Handle(handle).Value().(vanadium.BinaryANNIndex)
+	if err := binaryIndex.Train(dataSlice); err != nil {
+		return setError(errMsg, err)
+	}
+	*errMsg = nil
+	return 0
+}
+
+//export AddBinary
+func AddBinary(handle C.ulong, errMsg **C.char, keepData C.bool, data *C.uchar, dataLength C.int) C.int {
+	binaryIndex := cgo.Handle(handle).Value().(vanadium.BinaryANNIndex)
+	slice := unsafe.Slice(data, dataLength)
+	dataSlice := *(*[]uint8)(unsafe.Pointer(&slice))
+	if keepData {
+		copiedData := make([]uint8, dataLength)
+		copy(copiedData, dataSlice)
+		if err := binaryIndex.Add(copiedData); err != nil {
+			return setError(errMsg, err)
+		}
+	} else {
+		if err := binaryIndex.Add(dataSlice); err != nil {
+			return setError(errMsg, err)
+		}
+	}
+	*errMsg = nil
+	return 0
+}
+
+//export SearchBinary
+func SearchBinary(handle C.ulong, errMsg **C.char, query *C.uchar, queryLength C.int, k C.int,
+	outIndices **C.int, outDistances **C.int, outOffsets *C.int, outLengths *C.int) C.int {
+	binaryIndex := cgo.Handle(handle).Value().(vanadium.BinaryANNIndex)
+
+	slice := unsafe.Slice(query, queryLength)
+	querySlice := *(*[]uint8)(unsafe.Pointer(&slice))
+	resultIndices, resultDistances, err := binaryIndex.Search(querySlice, int(k))
+	if err != nil {
+		return setError(errMsg, err)
+	}
+
+	total := 0
+	for _, r := range resultIndices {
+		total += len(r)
+	}
+
+	indices := (*C.int)(C.malloc(C.size_t(total) * C.size_t(C.sizeof_int)))
+	distances := (*C.int)(C.malloc(C.size_t(total) * C.size_t(C.sizeof_int)))
+	offsets := unsafe.Slice(outOffsets, len(resultIndices))
+	lengths := unsafe.Slice(outLengths, len(resultIndices))
+
+	idx := 0
+	goIndices := unsafe.Slice(indices, total)
+	goDistances := unsafe.Slice(distances, total)
+	for i, r := range resultIndices {
+		offsets[i] = C.int(idx)
+		lengths[i] = C.int(len(r))
+		for j, val := range r {
+			goIndices[idx] = C.int(val)
+			goDistances[idx] = C.int(resultDistances[i][j])
+			idx++
+		}
+	}
+	*outIndices = indices
+	*outDistances = distances
+	*errMsg = nil
+	return 0
+}
+
+// index is satisfied by both vanadium.ANNIndex and vanadium.BinaryANNIndex.
+type index interface {
+	NumVectors() int
+	Save(enc *gob.Encoder) error
+	Describe() string
+	Info() vanadium.IndexInfo
+}
+
 //export NumVectors
 func NumVectors(handle C.ulong) C.int {
-	annIndex := cgo.Handle(handle).Value().(vanadium.ANNIndex)
+	annIndex := cgo.Handle(handle).Value().(index)
 	return C.int(annIndex.NumVectors())
 }
 
+// Describe returns the index spec. The caller must release it with FreeMemory.
+//
+//export Describe
+func Describe(handle C.ulong) *C.char {
+	annIndex := cgo.Handle(handle).Value().(index)
+	return C.CString(annIndex.Describe())
+}
+
+// Info stores the index configuration, sizes and memory usage as JSON in
+// outInfo. The caller must release it with FreeMemory.
+//
+//export Info
+func Info(handle C.ulong, errMsg **C.char, outInfo **C.char) C.int {
+	annIndex := cgo.Handle(handle).Value().(index)
+	info, err := json.Marshal(annIndex.Info())
+	if err != nil {
+		return setError(errMsg, err)
+	}
+	*outInfo = C.CString(string(info))
+	*errMsg = nil
+	return 0
+}
+
+// Save writes the index to path atomically through a temporary file.
+//
 //export Save
 func Save(handle C.ulong, errMsg **C.char, path *C.char) C.int {
-	annIndex := cgo.Handle(handle).Value().(vanadium.ANNIndex)
-	file, err := os.Create(C.GoString(path))
+	var err error
+	switch index := cgo.Handle(handle).Value().(type) {
+	case vanadium.ANNIndex:
+		err = vanadium.SaveFile(C.GoString(path), index)
+	case vanadium.BinaryANNIndex:
+		err = vanadium.SaveBinaryFile(C.GoString(path), index)
+	}
 	if err != nil {
-		*errMsg = C.CString(err.Error())
-		return 1
+		return setError(errMsg, err)
 	}
-	defer file.Close()
-	enc := gob.NewEncoder(file)
-	if err := annIndex.Save(enc); err != nil {
-		*errMsg = C.CString(err.Error())
-		return 1
+	*errMsg = nil
+	return 0
+}
+
+// SaveCompressed writes the index like Save, compressed with gzip at level,
+// from -2 (Huffman only) to 9 (best compression). Load detects compression.
+//
+//export SaveCompressed
+func SaveCompressed(handle C.ulong, errMsg **C.char, path *C.char, level C.int) C.int {
+	var err error
+	opt := vanadium.WithGzip(int(level))
+	switch index := cgo.Handle(handle).Value().(type) {
+	case vanadium.ANNIndex:
+		err = vanadium.SaveFile(C.GoString(path), index, opt)
+	case vanadium.BinaryANNIndex:
+		err = vanadium.SaveBinaryFile(C.GoString(path), index, opt)
+	}
+	if err != nil {
+		return setError(errMsg, err)
 	}
 	*errMsg = nil
 	return 0
@@ -222,17 +430,44 @@ func Save(handle C.ulong, errMsg **C.char, path *C.char) C.int {
 
 //export Load
 func Load(handle *C.ulong, errMsg **C.char, path *C.char) C.int {
-	file, err := os.Open(C.GoString(path))
+	annIndex, err := vanadium.LoadFile(C.GoString(path))
 	if err != nil {
-		*errMsg = C.CString(err.Error())
-		return 1
+		return setError(errMsg, err)
 	}
-	defer file.Close()
-	dec := gob.NewDecoder(file)
-	annIndex, err := vanadium.LoadIndex(dec)
+	h := cgo.NewHandle(annIndex)
+	*handle = C.ulong(h)
+	*errMsg = nil
+	return 0
+}
+
+// SaveToBuffer serializes the index into memory allocated with malloc. The
+// caller must release *outData with FreeMemory.
+//
+//export SaveToBuffer
+func SaveToBuffer(handle C.ulong, errMsg **C.char, outData **C.uchar, outLength *C.size_t) C.int {
+	var buf bytes.Buffer
+	annIndex := cgo.Handle(handle).Value().(index)
+	if err := annIndex.Save(gob.NewEncoder(&buf)); err != nil {
+		return setError(errMsg, err)
+	}
+
+	data := (*C.uchar)(C.malloc(C.size_t(max(buf.Len(), 1))))
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(data)), buf.Len()), buf.Bytes())
+	*outData = data
+	*outLength = C.size_t(buf.Len())
+	*errMsg = nil
+	return 0
+}
+
+// LoadFromBuffer loads an index serialized by SaveToBuffer, Save or
+// SaveCompressed. The buffer is not retained after the call returns.
+//
+//export LoadFromBuffer
+func LoadFromBuffer(handle *C.ulong, errMsg **C.char, data *C.uchar, length C.size_t) C.int {
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(data)), int(length))
+	annIndex, err := vanadium.ReadIndex(bytes.NewReader(buf))
 	if err != nil {
-		*errMsg = C.CString(err.Error())
-		return 1
+		return setError(errMsg, err)
 	}
 	h := cgo.NewHandle(annIndex)
 	*handle = C.ulong(h)
@@ -240,4 +475,112 @@ func Load(handle *C.ulong, errMsg **C.char, path *C.char) C.int {
 	return 0
 }
 
+//export LoadBinaryFromBuffer
+func LoadBinaryFromBuffer(handle *C.ulong, errMsg **C.char, data *C.uchar, length C.size_t) C.int {
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(data)), int(length))
+	binaryIndex, err := vanadium.ReadBinaryIndex(bytes.NewReader(buf))
+	if err != nil {
+		return setError(errMsg, err)
+	}
+	h := cgo.NewHandle(binaryIndex)
+	*handle = C.ulong(h)
+	*errMsg = nil
+	return 0
+}
+
+// SaveSharded writes a sharded index as a manifest plus one file per shard
+// into dir.
+//
+//export SaveSharded
+func SaveSharded(handle C.ulong, errMsg **C.char, dir *C.char) C.int {
+	shardedIndex, ok := cgo.Handle(handle).Value().(*vanadium.ShardedIndex)
+	if !ok {
+		return setError(errMsg, vanadium.ErrUnknownIndexType)
+	}
+	if err := shardedIndex.SaveDir(C.GoString(dir)); err != nil {
+		return setError(errMsg, err)
+	}
+	*errMsg = nil
+	return 0
+}
+
+//export LoadSharded
+func LoadSharded(handle *C.ulong, errMsg **C.char, dir *C.char) C.int {
+	annIndex, err := vanadium.LoadShardedIndex(C.GoString(dir))
+	if err != nil {
+		return setError(errMsg, err)
+	}
+	h := cgo.NewHandle(vanadium.ANNIndex(annIndex))
+	*handle = C.ulong(h)
+	*errMsg = nil
+	return 0
+}
+
+// SaveFAISS writes the index in the format read by faiss.read_index.
+//
+//export SaveFAISS
+func SaveFAISS(handle C.ulong, errMsg **C.char, path *C.char) C.int {
+	annIndex, ok := cgo.Handle(handle).Value().(vanadium.ANNIndex)
+	if !ok {
+		return setError(errMsg, vanadium.ErrUnsupportedFAISSIndex)
+	}
+	if err := vanadium.SaveFAISSFile(C.GoString(path), annIndex); err != nil {
+		return setError(errMsg, err)
+	}
+	*errMsg = nil
+	return 0
+}
+
+// LoadFAISS loads an index written by faiss.write_index.
+//
+//export LoadFAISS
+func LoadFAISS(handle *C.ulong, errMsg **C.char, path *C.char) C.int {
+	annIndex, err := vanadium.LoadFAISSFile(C.GoString(path))
+	if err != nil {
+		return setError(errMsg, err)
+	}
+	h := cgo.NewHandle(annIndex)
+	*handle = C.ulong(h)
+	*errMsg = nil
+	return 0
+}
+
+// SaveModel writes the trained model of the index without its vectors.
+//
+//export SaveModel
+func SaveModel(handle C.ulong, errMsg **C.char, path *C.char) C.int {
+	annIndex := cgo.Handle(handle).Value().(vanadium.ANNIndex)
+	if err := vanadium.SaveModelFile(C.GoString(path), annIndex); err != nil {
+		return setError(errMsg, err)
+	}
+	*errMsg = nil
+	return 0
+}
+
+// LoadModel loads a trained model as an empty index, ready for Add.
+//
+//export LoadModel
+func LoadModel(handle *C.ulong, errMsg **C.char, path *C.char) C.int {
+	annIndex, err := vanadium.LoadModelFile(C.GoString(path))
+	if err != nil {
+		return setError(errMsg, err)
+	}
+	h := cgo.NewHandle(annIndex)
+	*handle = C.ulong(h)
+	*errMsg = nil
+	return 0
+}
+
+//export LoadBinary
+func LoadBinary(handle *C.ulong, errMsg **C.char, path *C.char) C.int {
+	binaryIndex, err := vanadium.LoadBinaryFile(C.GoString(path))
+	if err != nil {
+		return setError(errMsg, err)
+	}
+	h := cgo.NewHandle(binaryIndex)
+	*handle = C.ulong(h)
+	*errMsg = nil
+	return 0
+}
+
 func main() {}
@@ -0,0 +1,497 @@
+package vanadium_index
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/gob"
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+
+	"github.com/monochromegane/kmeans"
+)
+
+type InitMethod int
+
+const (
+	InitKMeansPlusPlus InitMethod = iota
+	InitRandom
+)
+
+func (method InitMethod) kmeansInitMethod() int {
+	if method == InitRandom {
+		return kmeans.INIT_RANDOM
+	}
+	return kmeans.INIT_KMEANS_PLUS_PLUS
+}
+
+type clusterTrainConfig struct {
+	maxIterations int
+	tolerance     float32
+	// rng draws the initial centroids when training must be reproducible.
+	// When nil, they are drawn from a randomly seeded generator.
+	rng *rand.Rand
+	// miniBatchSize switches to mini-batch kmeans when greater than 0.
+	miniBatchSize       int
+	miniBatchIterations int
+}
+
+// trainCluster trains cluster on data and reports whether it converged, that
+// is whether the centroids moved less than the tolerance before the
+// iterations ran out. The initial centroids are drawn from config.rng, or
+// from a randomly seeded generator when it is nil. With a non-nil rng
+// training runs on a single goroutine, so that the same seed yields
+// bit-for-bit identical centroids.
+func trainCluster(cluster *kmeans.KMeans, data []float32, config clusterTrainConfig) (int, float32, bool, error) {
+	if config.miniBatchSize > 0 {
+		return trainMiniBatch(cluster, data, config)
+	}
+
+	state, err := clusterState(cluster)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if state.NumClusters > len(data)/state.NumFeatures {
+		return 0, 0, false, kmeans.ErrFewerClustersThanData
+	}
+
+	rng := config.rng
+	concurrency := 1
+	if rng == nil {
+		rng = newRand(0, false)
+		concurrency = runtime.NumCPU()
+	}
+	initMethod := state.InitMethod
+	switch initMethod {
+	case kmeans.INIT_RANDOM:
+		state.Centroids = initRandom(data, state.NumFeatures, state.NumClusters, rng)
+	default:
+		state.Centroids = initKMeansPlusPlus(data, state.NumFeatures, state.NumClusters, rng)
+	}
+	state.InitMethod = kmeans.INIT_NONE
+	err = setClusterState(cluster, state)
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	// kmeans.Train does not tell a run that converged on its last iteration
+	// from one that ran out of iterations, so it runs one iteration at a time
+	// and the tolerance is checked here.
+	centroids := state.Centroids
+	numIter := 0
+	loss := float32(0)
+	converged := false
+	for i := range config.maxIterations {
+		numIter = i
+		_, loss, err = cluster.Train(data, kmeans.WithMaxIterations(1), kmeans.WithConcurrency(concurrency))
+		if err != nil {
+			return 0, 0, false, err
+		}
+		next := cluster.Centroids()
+		if centroidShift(centroids, next) < float64(config.tolerance) {
+			converged = true
+			break
+		}
+		centroids = next
+	}
+
+	state, err = clusterState(cluster)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	state.InitMethod = initMethod
+	err = setClusterState(cluster, state)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return numIter, loss, converged, nil
+}
+
+// centroidShift returns how far the centroids moved from x to y relative to
+// the norm of y, the convergence measure used by the kmeans package.
+func centroidShift(x, y [][]float32) float64 {
+	diff := float64(0)
+	norm := float64(0)
+	for c := range y {
+		diff += float64(squaredEuclideanDistance(x[c], y[c]))
+		for _, value := range y[c] {
+			norm += float64(value) * float64(value)
+		}
+	}
+	return math.Sqrt(diff) / math.Sqrt(norm)
+}
+
+// trainMiniBatch runs mini-batch kmeans: each iteration assigns a random batch
+// of vectors and moves their centroids with a per-centroid learning rate. It
+// trades some accuracy for training time that does not grow with the size of
+// data.
+func trainMiniBatch(cluster *kmeans.KMeans, data []float32, config clusterTrainConfig) (int, float32, bool, error) {
+	state, err := clusterState(cluster)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	numFeatures := state.NumFeatures
+	numClusters := state.NumClusters
+	N := len(data) / numFeatures
+	if numClusters > N {
+		return 0, 0, false, kmeans.ErrFewerClustersThanData
+	}
+
+	rng := config.rng
+	if rng == nil {
+		rng = newRand(0, false)
+	}
+
+	initData := sampleVectors(data, numFeatures, max(10*numClusters, config.miniBatchSize), rng)
+	var centroids [][]float32
+	switch state.InitMethod {
+	case kmeans.INIT_RANDOM:
+		centroids = initRandom(initData, numFeatures, numClusters, rng)
+	default:
+		centroids = initKMeansPlusPlus(initData, numFeatures, numClusters, rng)
+	}
+
+	counts := make([]int, numClusters)
+	batch := make([]int, config.miniBatchSize)
+	assignments := make([]int, config.miniBatchSize)
+	numIter := 0
+	loss := float32(0)
+	converged := false
+	for i := range config.miniBatchIterations {
+		numIter = i
+		loss = 0
+		for b := range batch {
+			batch[b] = rng.IntN(N)
+			x := data[batch[b]*numFeatures : (batch[b]+1)*numFeatures]
+			nearest, dist := nearestCentroid(x, centroids)
+			assignments[b] = nearest
+			loss += dist
+		}
+
+		centroidDiff := float32(0)
+		frobNorm := float32(0)
+		for b, n := range batch {
+			c := assignments[b]
+			counts[c]++
+			eta := 1 / float32(counts[c])
+			x := data[n*numFeatures : (n+1)*numFeatures]
+			for d := range numFeatures {
+				diff := eta * (x[d] - centroids[c][d])
+				centroids[c][d] += diff
+				centroidDiff += diff * diff
+			}
+		}
+		for c := range numClusters {
+			for d := range numFeatures {
+				frobNorm += centroids[c][d] * centroids[c][d]
+			}
+		}
+
+		if math.Sqrt(float64(centroidDiff))/math.Sqrt(float64(frobNorm)) < float64(config.tolerance) {
+			converged = true
+			break
+		}
+	}
+
+	state.Centroids = centroids
+	err = setClusterState(cluster, state)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return numIter, loss, converged, nil
+}
+
+func nearestCentroid(x []float32, centroids [][]float32) (int, float32) {
+	nearest := 0
+	nearestDist := squaredEuclideanDistance(x, centroids[0])
+	for c := 1; c < len(centroids); c++ {
+		dist := squaredEuclideanDistance(x, centroids[c])
+		if dist < nearestDist {
+			nearest, nearestDist = c, dist
+		}
+	}
+	return nearest, nearestDist
+}
+
+func initRandom(data []float32, numFeatures, numClusters int, rng *rand.Rand) [][]float32 {
+	N := len(data) / numFeatures
+	centroids := make([][]float32, numClusters)
+	for i, n := range rng.Perm(N)[:numClusters] {
+		centroids[i] = make([]float32, numFeatures)
+		copy(centroids[i], data[n*numFeatures:(n+1)*numFeatures])
+	}
+	return centroids
+}
+
+func initKMeansPlusPlus(data []float32, numFeatures, numClusters int, rng *rand.Rand) [][]float32 {
+	N := len(data) / numFeatures
+	centroids := make([][]float32, 0, numClusters)
+	distances := make([]float64, N)
+
+	next := rng.IntN(N)
+	for {
+		centroid := make([]float32, numFeatures)
+		copy(centroid, data[next*numFeatures:(next+1)*numFeatures])
+		centroids = append(centroids, centroid)
+		if len(centroids) == numClusters {
+			break
+		}
+
+		total := float64(0)
+		for n := range N {
+			dist := float64(squaredEuclideanDistance(data[n*numFeatures:(n+1)*numFeatures], centroid))
+			if len(centroids) == 1 || dist < distances[n] {
+				distances[n] = dist
+			}
+			total += distances[n]
+		}
+
+		if total == 0 {
+			next = rng.IntN(N)
+			continue
+		}
+		threshold := rng.Float64() * total
+		next = N - 1
+		cumSum := float64(0)
+		for n := range N {
+			cumSum += distances[n]
+			if cumSum >= threshold {
+				next = n
+				break
+			}
+		}
+	}
+	return centroids
+}
+
+// setCentroids replaces the centroids of a kmeans model.
+func setCentroids(cluster *kmeans.KMeans, centroids [][]float32) error {
+	state, err := clusterState(cluster)
+	if err != nil {
+		return err
+	}
+	state.Centroids = centroids
+	return setClusterState(cluster, state)
+}
+
+// copyCluster returns an independent copy of a kmeans model.
+func copyCluster(cluster *kmeans.KMeans) (*kmeans.KMeans, error) {
+	state, err := clusterState(cluster)
+	if err != nil {
+		return nil, err
+	}
+	copied := &kmeans.KMeans{}
+	err = setClusterState(copied, state)
+	if err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
+
+// clusterState returns a copy of the state of a kmeans model. The kmeans
+// package exposes its state only through gob, so the state is round-tripped.
+func clusterState(cluster *kmeans.KMeans) (*kmeans.KMeansState, error) {
+	var buf bytes.Buffer
+	err := cluster.Encode(gob.NewEncoder(&buf))
+	if err != nil {
+		return nil, err
+	}
+	state := &kmeans.KMeansState{}
+	err = gob.NewDecoder(&buf).Decode(state)
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// setClusterState replaces the state of a kmeans model. It loads a fresh model
+// instead of decoding into the existing one, because gob leaves fields whose
+// new value is zero (such as INIT_NONE) untouched.
+func setClusterState(cluster *kmeans.KMeans, state *kmeans.KMeansState) error {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(state)
+	if err != nil {
+		return err
+	}
+	loaded, err := kmeans.LoadKMeans(gob.NewDecoder(&buf))
+	if err != nil {
+		return err
+	}
+	*cluster = *loaded
+	return nil
+}
+
+// balanceClusters splits clusters holding more than maxSize vectors. Each
+// split reuses the slot of the smallest cluster, whose vectors move to their
+// nearest remaining centroid. Vectors are reassigned to their nearest centroid
+// after every split, as Add assigns them, so that the sizes checked are the
+// list sizes the data will have. It stops when no cluster exceeds maxSize and
+// none is below minSize, or when no further split is possible.
+func balanceClusters(
+	data []float32,
+	numFeatures int,
+	centroids [][]float32,
+	assignments []int,
+	maxSize, minSize int,
+	config clusterTrainConfig,
+) ([][]float32, int, error) {
+	numClusters := len(centroids)
+	if numClusters < 3 {
+		return centroids, 0, nil
+	}
+
+	sizes := make([]int, numClusters)
+	for _, c := range assignments {
+		sizes[c]++
+	}
+
+	numSplits := 0
+	for range maxBalanceSplitsPerCluster * numClusters {
+		largest, smallest := 0, 0
+		for c := range numClusters {
+			if sizes[c] > sizes[largest] {
+				largest = c
+			}
+			if sizes[c] < sizes[smallest] {
+				smallest = c
+			}
+		}
+		if largest == smallest || (sizes[largest] <= maxSize && sizes[smallest] >= minSize) {
+			break
+		}
+		if sizes[largest] < 2 {
+			break
+		}
+
+		largestData := make([]float32, 0, sizes[largest]*numFeatures)
+		for n, c := range assignments {
+			if c == largest {
+				largestData = append(largestData, data[n*numFeatures:(n+1)*numFeatures]...)
+			}
+		}
+
+		split, err := kmeans.NewKMeans(2, numFeatures, kmeans.WithInitMethod(kmeans.INIT_KMEANS_PLUS_PLUS))
+		if err != nil {
+			return nil, 0, err
+		}
+		_, _, _, err = trainCluster(split, largestData, config)
+		if err != nil {
+			return nil, 0, err
+		}
+		splitCentroids := splitInHalves(largestData, numFeatures, split.Centroids())
+		if splitCentroids == nil {
+			break
+		}
+
+		centroids[largest] = splitCentroids[0]
+		centroids[smallest] = splitCentroids[1]
+		clear(sizes)
+		for n := range assignments {
+			assignments[n], _ = nearestCentroid(data[n*numFeatures:(n+1)*numFeatures], centroids)
+			sizes[assignments[n]]++
+		}
+		numSplits++
+	}
+
+	return centroids, numSplits, nil
+}
+
+// maxBalanceSplitsPerCluster bounds the splits made by balanceClusters. A
+// split can leave a new small cluster behind, so balancing may take more than
+// one split per cluster.
+const maxBalanceSplitsPerCluster = 4
+
+// splitInHalves splits data at the median of its projection on the axis
+// between the centroids of a 2-means split and returns the mean of each half.
+// 2-means alone may split off a few outliers and leave the bulk of the data in
+// one cluster. It returns nil when the vectors do not spread along the axis.
+func splitInHalves(data []float32, numFeatures int, centroids [][]float32) [][]float32 {
+	numVectors := len(data) / numFeatures
+	axis := make([]float32, numFeatures)
+	for i := range axis {
+		axis[i] = centroids[1][i] - centroids[0][i]
+	}
+	projections := make([]float32, numVectors)
+	for n := range numVectors {
+		for i, v := range data[n*numFeatures : (n+1)*numFeatures] {
+			projections[n] += v * axis[i]
+		}
+	}
+	order := make([]int, numVectors)
+	for n := range order {
+		order[n] = n
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(projections[a], projections[b])
+	})
+	if projections[order[0]] == projections[order[numVectors-1]] {
+		return nil
+	}
+
+	halves := [][]float32{make([]float32, numFeatures), make([]float32, numFeatures)}
+	for rank, n := range order {
+		half := halves[0]
+		if rank >= numVectors/2 {
+			half = halves[1]
+		}
+		for i, v := range data[n*numFeatures : (n+1)*numFeatures] {
+			half[i] += v
+		}
+	}
+	for h, size := range []int{numVectors / 2, numVectors - numVectors/2} {
+		for i := range halves[h] {
+			halves[h][i] /= float32(size)
+		}
+	}
+	return halves
+}
+
+// newRand returns a generator seeded with seed when seeded is true, and a
+// randomly seeded one otherwise.
+func newRand(seed uint64, seeded bool) *rand.Rand {
+	if !seeded {
+		return rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+	return rand.New(rand.NewPCG(seed, seed))
+}
+
+// subSeed derives the seed of the i-th sub-model, such as a PQ subspace or an
+// IVF list, from the seed of its parent.
+func subSeed(seed uint64, i int) uint64 {
+	return seed ^ (uint64(i+1) * 0x9E3779B97F4A7C15)
+}
+
+// sampleVectors draws sampleSize vectors from data without replacement,
+// preserving their order. data is returned as is when sampleSize is not
+// positive or not smaller than the number of vectors.
+func sampleVectors(data []float32, numFeatures, sampleSize int, rng *rand.Rand) []float32 {
+	numVectors := len(data) / numFeatures
+	if sampleSize <= 0 || sampleSize >= numVectors {
+		return data
+	}
+
+	sample := make([]float32, 0, sampleSize*numFeatures)
+	for n := 0; n < numVectors && len(sample) < sampleSize*numFeatures; n++ {
+		remaining := sampleSize - len(sample)/numFeatures
+		if rng.IntN(numVectors-n) < remaining {
+			sample = append(sample, data[n*numFeatures:(n+1)*numFeatures]...)
+		}
+	}
+	return sample
+}
+
+func squaredEuclideanDistance(x, y []float32) float32 {
+	distance := float32(0)
+	for i := range x {
+		diff := x[i] - y[i]
+		distance += diff * diff
+	}
+	return distance
+}
+
+func equalCentroids(x, y [][]float32) bool {
+	return slices.EqualFunc(x, y, func(a, b []float32) bool {
+		return slices.Equal(a, b)
+	})
+}
@@ -0,0 +1,133 @@
+package vanadium_index
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/monochromegane/kmeans"
+)
+
+func TestSampleVectors(t *testing.T) {
+	numFeatures := 2
+	data := make([]float32, 100*numFeatures)
+	for i := range data {
+		data[i] = float32(i / numFeatures)
+	}
+
+	sample1 := sampleVectors(data, numFeatures, 10, newRand(42, true))
+	sample2 := sampleVectors(data, numFeatures, 10, newRand(42, true))
+	if len(sample1) != 10*numFeatures {
+		t.Fatalf("len(sample) = %d, expected %d", len(sample1), 10*numFeatures)
+	}
+	for i := range sample1 {
+		if sample1[i] != sample2[i] {
+			t.Fatalf("samples with the same seed differ at %d: %f != %f", i, sample1[i], sample2[i])
+		}
+	}
+	for v := range 10 {
+		if sample1[v*numFeatures] != sample1[v*numFeatures+1] {
+			t.Fatalf("sampled vector %d is not a row of data: %v", v, sample1[v*numFeatures:(v+1)*numFeatures])
+		}
+		if v > 0 && sample1[v*numFeatures] <= sample1[(v-1)*numFeatures] {
+			t.Fatalf("sample is not in data order: %v", sample1)
+		}
+	}
+
+	all := sampleVectors(data, numFeatures, 1000, newRand(42, true))
+	if len(all) != len(data) {
+		t.Fatalf("len(all) = %d, expected %d", len(all), len(data))
+	}
+}
+
+func TestSetCentroids(t *testing.T) {
+	cluster, _ := kmeans.NewKMeans(2, 2)
+	centroids := [][]float32{{0, 0}, {1, 1}}
+	err := setCentroids(cluster, centroids)
+	if err != nil {
+		t.Fatalf("Failed to set centroids: %v", err)
+	}
+
+	expected := []int{0, 1}
+	err = cluster.Predict([]float32{0.1, 0.1, 0.9, 0.9}, func(row int, minCol int, minVal float32) error {
+		if minCol != expected[row] {
+			t.Fatalf("minCol = %d, expected %d", minCol, expected[row])
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to predict: %v", err)
+	}
+}
+
+func TestTrainMiniBatch(t *testing.T) {
+	numFeatures := 2
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 0, 1000*numFeatures)
+	for n := range 1000 {
+		offset := float32(0)
+		if n%2 == 1 {
+			offset = 10
+		}
+		data = append(data, offset+rng.Float32(), offset+rng.Float32())
+	}
+
+	cluster, _ := kmeans.NewKMeans(2, numFeatures)
+	_, _, _, err := trainCluster(cluster, data, clusterTrainConfig{
+		maxIterations:       100,
+		tolerance:           1e-4,
+		rng:                 newRand(42, true),
+		miniBatchSize:       50,
+		miniBatchIterations: 100,
+	})
+	if err != nil {
+		t.Fatalf("Failed to train: %v", err)
+	}
+
+	centroids := cluster.Centroids()
+	if centroids[0][0] > centroids[1][0] {
+		centroids[0], centroids[1] = centroids[1], centroids[0]
+	}
+	expected := [][]float32{{0.5, 0.5}, {10.5, 10.5}}
+	for c := range expected {
+		for d := range expected[c] {
+			if diff := centroids[c][d] - expected[c][d]; diff > 0.2 || diff < -0.2 {
+				t.Fatalf("centroids[%d] = %v, expected about %v", c, centroids[c], expected[c])
+			}
+		}
+	}
+}
+
+func TestTrainClusterConvergedOnLastIteration(t *testing.T) {
+	numFeatures := 2
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 1000*numFeatures)
+	for i := range data {
+		data[i] = rng.Float32()
+	}
+	train := func(maxIterations int) (int, bool) {
+		cluster, _ := kmeans.NewKMeans(8, numFeatures)
+		numIter, _, converged, err := trainCluster(cluster, data, clusterTrainConfig{
+			maxIterations: maxIterations,
+			tolerance:     1e-4,
+			rng:           newRand(1, true),
+		})
+		if err != nil {
+			t.Fatalf("Failed to train: %v", err)
+		}
+		return numIter + 1, converged
+	}
+
+	iterations, converged := train(1000)
+	if !converged || iterations < 2 {
+		t.Fatalf("expected convergence after more than one iteration, got %d %v", iterations, converged)
+	}
+	// Converging on the last allowed iteration is still convergence.
+	lastIterations, converged := train(iterations)
+	if !converged || lastIterations != iterations {
+		t.Fatalf("expected convergence on iteration %d, got %d %v", iterations, lastIterations, converged)
+	}
+	_, converged = train(iterations - 1)
+	if converged {
+		t.Fatalf("expected no convergence within %d iterations", iterations-1)
+	}
+}
@@ -0,0 +1,256 @@
+package vanadium_index
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+)
+
+// DurableIndex logs every Add to a write-ahead log before applying it, so
+// that vectors added since the last snapshot survive a crash. Checkpoint
+// writes a fresh snapshot and truncates the log.
+//
+// Each log record carries the id of its first vector. Records already
+// contained in the snapshot are skipped on replay, so a crash between writing
+// a snapshot and truncating the log does not add vectors twice.
+type DurableIndex struct {
+	ANNIndex
+	config       *DurableIndexConfig
+	snapshotPath string
+	numFeatures  int
+	indexType    IndexType
+	wal          *os.File
+	numLogged    int
+}
+
+type DurableIndexConfig struct {
+	CheckpointEvery int
+	NoSync          bool
+}
+
+const walHeaderSize = 16
+
+// OpenDurableIndex loads the snapshot at snapshotPath, replays the log at
+// walPath on top of it and keeps logging to walPath. newIndex creates the
+// index when no snapshot exists yet.
+func OpenDurableIndex(snapshotPath, walPath string, newIndex func() (ANNIndex, error), opts ...DurableIndexOption) (*DurableIndex, error) {
+	index, err := LoadFile(snapshotPath)
+	if errors.Is(err, os.ErrNotExist) {
+		index, err = newIndex()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewDurableIndex(index, snapshotPath, walPath, opts...)
+}
+
+// NewDurableIndex replays the log at walPath into index, which must be the
+// state saved at snapshotPath, and keeps logging to walPath.
+func NewDurableIndex(index ANNIndex, snapshotPath, walPath string, opts ...DurableIndexOption) (*DurableIndex, error) {
+	info := index.Info()
+	durable := &DurableIndex{
+		ANNIndex: index,
+		// Default values
+		config:       &DurableIndexConfig{},
+		snapshotPath: snapshotPath,
+		numFeatures:  info.NumFeatures,
+		indexType:    info.IndexType,
+	}
+	for _, opt := range opts {
+		err := opt(durable.config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	wal, err := os.OpenFile(walPath, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	durable.wal = wal
+	err = durable.replay()
+	if err != nil {
+		wal.Close()
+		return nil, err
+	}
+	return durable, nil
+}
+
+// Add logs data and then adds it to the index. When the periodic checkpoint
+// fails, data is already logged and in the index, and Add returns an error
+// wrapping ErrCheckpointFailed; retrying the Add would store data twice.
+func (index *DurableIndex) Add(data []float32) error {
+	numFeatures := index.numFeatures
+	if len(data) == 0 {
+		return newIndexError(OpAdd, index.indexType, ErrEmptyData)
+	}
+	if len(data)%numFeatures != 0 {
+		return newIndexError(OpAdd, index.indexType, ErrInvalidDataLength, "dataLength", len(data), "numFeatures", numFeatures)
+	}
+
+	offset, err := index.wal.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	err = index.writeRecord(data)
+	if err != nil {
+		return err
+	}
+
+	err = index.ANNIndex.Add(data)
+	if err != nil {
+		// Drop the record so that replay does not apply a failed Add.
+		truncErr := index.wal.Truncate(offset)
+		return errors.Join(err, truncErr)
+	}
+
+	index.numLogged += len(data) / numFeatures
+	if index.config.CheckpointEvery > 0 && index.numLogged >= index.config.CheckpointEvery {
+		err = index.Checkpoint()
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrCheckpointFailed, err)
+		}
+	}
+	return nil
+}
+
+// Train trains the index and checkpoints, since the log only records adds.
+func (index *DurableIndex) Train(data []float32) error {
+	err := index.ANNIndex.Train(data)
+	if err != nil {
+		return err
+	}
+	return index.Checkpoint()
+}
+
+// Merge merges other into the index and checkpoints, since the log only
+// records adds.
+func (index *DurableIndex) Merge(other ANNIndex) error {
+	err := index.ANNIndex.Merge(other)
+	if err != nil {
+		return err
+	}
+	return index.Checkpoint()
+}
+
+// Checkpoint writes a snapshot of the index and truncates the log.
+func (index *DurableIndex) Checkpoint() error {
+	err := SaveFile(index.snapshotPath, index.ANNIndex)
+	if err != nil {
+		return err
+	}
+	err = index.wal.Truncate(0)
+	if err != nil {
+		return err
+	}
+	index.numLogged = 0
+	return index.wal.Sync()
+}
+
+// Close closes the log. It does not checkpoint.
+func (index *DurableIndex) Close() error {
+	return index.wal.Close()
+}
+
+// writeRecord appends a record of a little-endian header holding the CRC-32
+// of the rest of the record, the number of values and the id of the first
+// vector, followed by the values.
+func (index *DurableIndex) writeRecord(data []float32) error {
+	record := make([]byte, walHeaderSize+len(data)*4)
+	binary.LittleEndian.PutUint32(record[4:], uint32(len(data)))
+	binary.LittleEndian.PutUint64(record[8:], uint64(index.NumVectors()))
+	for i, value := range data {
+		binary.LittleEndian.PutUint32(record[walHeaderSize+i*4:], math.Float32bits(value))
+	}
+	binary.LittleEndian.PutUint32(record[0:], crc32.ChecksumIEEE(record[4:]))
+
+	_, err := index.wal.Write(record)
+	if err != nil {
+		return err
+	}
+	if index.config.NoSync {
+		return nil
+	}
+	return index.wal.Sync()
+}
+
+// replay applies the records of the log that are not part of the index yet.
+// A torn record at the end of the log, left by a crash during Add, is
+// discarded. A record that fails its checksum and is followed by more
+// records is corruption rather than a torn write and fails with
+// ErrCorruptLog.
+func (index *DurableIndex) replay() error {
+	stat, err := index.wal.Stat()
+	if err != nil {
+		return err
+	}
+	size := stat.Size()
+	_, err = index.wal.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(index.wal)
+	numFeatures := index.numFeatures
+	offset := int64(0)
+	header := make([]byte, walHeaderSize)
+	for size-offset >= walHeaderSize {
+		_, err = io.ReadFull(reader, header)
+		if err != nil {
+			return err
+		}
+		numValues := int(binary.LittleEndian.Uint32(header[4:]))
+		firstID := int(binary.LittleEndian.Uint64(header[8:]))
+		recordSize := int64(walHeaderSize) + int64(numValues)*4
+		if recordSize > size-offset {
+			// The record runs past the end of the log.
+			break
+		}
+		payload := make([]byte, numValues*4)
+		_, err = io.ReadFull(reader, payload)
+		if err != nil {
+			return err
+		}
+		crc := crc32.NewIEEE()
+		crc.Write(header[4:])
+		crc.Write(payload)
+		if crc.Sum32() != binary.LittleEndian.Uint32(header[0:]) {
+			if offset+recordSize == size {
+				break
+			}
+			return fmt.Errorf("%w: record at offset %d fails its checksum", ErrCorruptLog, offset)
+		}
+
+		numVectors := index.NumVectors()
+		if firstID > numVectors || numValues%numFeatures != 0 {
+			return fmt.Errorf("%w: record at offset %d starts at vector %d, index has %d", ErrCorruptLog, offset, firstID, numVectors)
+		}
+		offset += recordSize
+		index.numLogged += numValues / numFeatures
+
+		// Skip the vectors the snapshot already contains.
+		skip := (numVectors - firstID) * numFeatures
+		if skip >= numValues {
+			continue
+		}
+		data := make([]float32, numValues-skip)
+		for i := range data {
+			data[i] = math.Float32frombits(binary.LittleEndian.Uint32(payload[(skip+i)*4:]))
+		}
+		err = index.ANNIndex.Add(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = index.wal.Truncate(offset)
+	if err != nil {
+		return err
+	}
+	_, err = index.wal.Seek(0, io.SeekEnd)
+	return err
+}
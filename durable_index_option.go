@@ -0,0 +1,24 @@
+package vanadium_index
+
+type DurableIndexOption func(*DurableIndexConfig) error
+
+// WithDurableCheckpointEvery writes a snapshot and truncates the log after
+// every numVectors vectors added since the last checkpoint.
+func WithDurableCheckpointEvery(numVectors int) DurableIndexOption {
+	return func(config *DurableIndexConfig) error {
+		if numVectors <= 0 {
+			return ErrInvalidCheckpointInterval
+		}
+		config.CheckpointEvery = numVectors
+		return nil
+	}
+}
+
+// WithDurableNoSync skips the fsync after each logged Add. A crash may then
+// lose the most recent adds, but the log stays consistent.
+func WithDurableNoSync() DurableIndexOption {
+	return func(config *DurableIndexConfig) error {
+		config.NoSync = true
+		return nil
+	}
+}
@@ -0,0 +1,229 @@
+package vanadium_index
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newDurableFlat(t *testing.T, dir string, opts ...DurableIndexOption) *DurableIndex {
+	t.Helper()
+	index, err := OpenDurableIndex(
+		filepath.Join(dir, "index"),
+		filepath.Join(dir, "wal"),
+		func() (ANNIndex, error) { return NewIndex(2, AsFlat()) },
+		opts...,
+	)
+	if err != nil {
+		t.Fatalf("Failed to open index: %v", err)
+	}
+	return index
+}
+
+func TestDurableIndexReplay(t *testing.T) {
+	dir := t.TempDir()
+	index := newDurableFlat(t, dir)
+	for _, data := range [][]float32{{0, 0, 1, 1}, {2, 2}} {
+		err := index.Add(data)
+		if err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+	}
+	err := index.Add([]float32{1, 2, 3})
+	if !errors.Is(err, ErrInvalidDataLength) {
+		t.Fatalf("expected %v, got %v", ErrInvalidDataLength, err)
+	}
+	index.Close()
+
+	// Simulate a crash in the middle of writing a record.
+	wal, _ := os.OpenFile(filepath.Join(dir, "wal"), os.O_APPEND|os.O_WRONLY, 0o644)
+	wal.Write([]byte{1, 2, 3, 4, 5})
+	wal.Close()
+
+	reopened := newDurableFlat(t, dir)
+	defer reopened.Close()
+	if reopened.NumVectors() != 3 {
+		t.Fatalf("expected 3 vectors after replay, got %d", reopened.NumVectors())
+	}
+	results, _, err := reopened.Search([]float32{2, 2}, 1)
+	if err != nil || results[0][0] != 2 {
+		t.Fatalf("expected vector 2, got %v %v", results, err)
+	}
+
+	err = reopened.Add([]float32{3, 3})
+	if err != nil {
+		t.Fatalf("Failed to add data after replay: %v", err)
+	}
+	reopened.Close()
+	again := newDurableFlat(t, dir)
+	defer again.Close()
+	if again.NumVectors() != 4 {
+		t.Fatalf("expected 4 vectors after replay, got %d", again.NumVectors())
+	}
+}
+
+func TestDurableIndexCorruptLog(t *testing.T) {
+	dir := t.TempDir()
+	walPath := filepath.Join(dir, "wal")
+	index := newDurableFlat(t, dir)
+	for _, data := range [][]float32{{0, 0}, {1, 1}, {2, 2}} {
+		err := index.Add(data)
+		if err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+	}
+	index.Close()
+	log, _ := os.ReadFile(walPath)
+	recordSize := walHeaderSize + 2*4
+
+	// A bad checksum followed by more records is not a torn write, and the
+	// records after it must be kept.
+	corrupt := append([]byte{}, log...)
+	corrupt[walHeaderSize] ^= 0xff
+	os.WriteFile(walPath, corrupt, 0o644)
+	_, err := OpenDurableIndex(filepath.Join(dir, "index"), walPath, func() (ANNIndex, error) { return NewIndex(2, AsFlat()) })
+	if !errors.Is(err, ErrCorruptLog) {
+		t.Fatalf("expected %v, got %v", ErrCorruptLog, err)
+	}
+	stat, _ := os.Stat(walPath)
+	if stat.Size() != int64(len(log)) {
+		t.Fatalf("expected the log to be kept, got %d bytes", stat.Size())
+	}
+
+	// A bad checksum in the last record is a torn write.
+	torn := append([]byte{}, log...)
+	torn[2*recordSize+walHeaderSize] ^= 0xff
+	os.WriteFile(walPath, torn, 0o644)
+	reopened := newDurableFlat(t, dir)
+	if reopened.NumVectors() != 2 {
+		t.Fatalf("expected 2 vectors after replay, got %d", reopened.NumVectors())
+	}
+	reopened.Close()
+
+	// A header claiming more values than the log holds is a torn write too.
+	wal, _ := os.OpenFile(walPath, os.O_APPEND|os.O_WRONLY, 0o644)
+	wal.Write([]byte{0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 2, 0, 0, 0, 0, 0, 0, 0})
+	wal.Close()
+	reopened = newDurableFlat(t, dir)
+	defer reopened.Close()
+	if reopened.NumVectors() != 2 {
+		t.Fatalf("expected 2 vectors after replay, got %d", reopened.NumVectors())
+	}
+	stat, _ = os.Stat(walPath)
+	if stat.Size() != int64(2*recordSize) {
+		t.Fatalf("expected the torn record to be truncated, got %d bytes", stat.Size())
+	}
+}
+
+func TestDurableIndexCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	walPath := filepath.Join(dir, "wal")
+	index := newDurableFlat(t, dir)
+	err := index.Add([]float32{0, 0, 1, 1})
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	log, _ := os.ReadFile(walPath)
+
+	err = index.Checkpoint()
+	if err != nil {
+		t.Fatalf("Failed to checkpoint: %v", err)
+	}
+	stat, _ := os.Stat(walPath)
+	if stat.Size() != 0 {
+		t.Fatalf("expected empty log after checkpoint, got %d bytes", stat.Size())
+	}
+	index.Close()
+
+	// Simulate a crash between writing the snapshot and truncating the log.
+	os.WriteFile(walPath, log, 0o644)
+	reopened := newDurableFlat(t, dir)
+	defer reopened.Close()
+	if reopened.NumVectors() != 2 {
+		t.Fatalf("expected 2 vectors without duplicates, got %d", reopened.NumVectors())
+	}
+}
+
+func TestDurableIndexWithCheckpointEvery(t *testing.T) {
+	dir := t.TempDir()
+	index := newDurableFlat(t, dir, WithDurableCheckpointEvery(2), WithDurableNoSync())
+	defer index.Close()
+	err := index.Add([]float32{0, 0})
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "index")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected no snapshot before the interval, got %v", err)
+	}
+	err = index.Add([]float32{1, 1})
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	snapshot, err := LoadFile(filepath.Join(dir, "index"))
+	if err != nil {
+		t.Fatalf("Failed to load snapshot: %v", err)
+	}
+	if snapshot.NumVectors() != 2 {
+		t.Fatalf("expected 2 vectors in snapshot, got %d", snapshot.NumVectors())
+	}
+
+	_, err = NewDurableIndex(snapshot, filepath.Join(dir, "index"), filepath.Join(dir, "wal"), WithDurableCheckpointEvery(0))
+	if !errors.Is(err, ErrInvalidCheckpointInterval) {
+		t.Fatalf("expected %v, got %v", ErrInvalidCheckpointInterval, err)
+	}
+}
+
+func TestDurableIndexCheckpointFailed(t *testing.T) {
+	dir := t.TempDir()
+	index := newDurableFlat(t, dir, WithDurableCheckpointEvery(1), WithDurableNoSync())
+	defer index.Close()
+
+	// A directory in place of the snapshot makes the checkpoint fail.
+	err := os.Mkdir(filepath.Join(dir, "index"), 0o755)
+	if err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	err = index.Add([]float32{0, 0})
+	if !errors.Is(err, ErrCheckpointFailed) {
+		t.Fatalf("expected %v, got %v", ErrCheckpointFailed, err)
+	}
+	if index.NumVectors() != 1 {
+		t.Fatalf("expected the vector to be added, got %d vectors", index.NumVectors())
+	}
+}
+
+func TestDurableIndexTrainCheckpoints(t *testing.T) {
+	dir := t.TempDir()
+	data := []float32{
+		0.1, 0.2, 0.3, 0.4,
+		0.5, 0.6, 0.7, 0.8,
+		0.9, 1.0, 1.1, 1.2,
+		1.3, 1.4, 1.5, 1.6,
+	}
+	newIndex := func() (ANNIndex, error) {
+		return NewIndex(4, AsPQ(2, 2, WithPQMaxIterations(10)))
+	}
+	index, err := OpenDurableIndex(filepath.Join(dir, "index"), filepath.Join(dir, "wal"), newIndex)
+	if err != nil {
+		t.Fatalf("Failed to open index: %v", err)
+	}
+	err = index.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+	err = index.Add(data)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	index.Close()
+
+	reopened, err := OpenDurableIndex(filepath.Join(dir, "index"), filepath.Join(dir, "wal"), newIndex)
+	if err != nil {
+		t.Fatalf("Failed to open index: %v", err)
+	}
+	defer reopened.Close()
+	if reopened.NumVectors() != 4 || !reopened.Info().IsTrained {
+		t.Fatalf("expected trained index with 4 vectors, got %+v", reopened.Info())
+	}
+}
@@ -3,6 +3,8 @@ package vanadium_index
 import (
 	"fmt"
 	"math"
+	"sort"
+	"strings"
 )
 
 var ErrInvalidDataLength = fmt.Errorf("data length must be divisible by the number of features")
@@ -24,3 +26,139 @@ var ErrInvalidTol = fmt.Errorf("tolerance must be greater than 0")
 var ErrInvalidPQOptions = fmt.Errorf("pq options can use only in ProductQuantizationIndex")
 
 var ErrNotTrained = fmt.Errorf("index is not trained")
+
+var ErrInsufficientTrainingData = fmt.Errorf("number of training vectors must be greater than or equal to the number of clusters")
+
+var ErrInvalidKFactor = fmt.Errorf("k factor must be greater than 0")
+
+var ErrInvalidNumBits = fmt.Errorf("number of bits must be greater than 0 and divisible by 8")
+
+var ErrInvalidHNSWParameters = fmt.Errorf("hnsw parameters must be greater than 0")
+
+var ErrInvalidListSizeRatio = fmt.Errorf("max list size ratio must be at least 1 and min list size ratio must be in [0, 1)")
+
+var ErrInvalidSampleSize = fmt.Errorf("sample size must be greater than 0")
+
+var ErrInvalidInitMethod = fmt.Errorf("init method must be InitKMeansPlusPlus or InitRandom")
+
+var ErrInvalidBatchSize = fmt.Errorf("batch size must be greater than 0")
+
+var ErrUnknownIndexType = fmt.Errorf("unknown index type")
+
+var ErrUnknownCodeType = fmt.Errorf("unknown code type")
+
+var ErrInvalidIndexSpec = fmt.Errorf("invalid index spec")
+
+var ErrInvalidNumShards = fmt.Errorf("number of shards must be greater than 0")
+
+var ErrInvalidManifest = fmt.Errorf("invalid sharded index manifest")
+
+var ErrIndexNotEmpty = fmt.Errorf("index must be empty to be trained")
+
+var ErrInvalidCheckpointInterval = fmt.Errorf("checkpoint interval must be greater than 0")
+
+var ErrCorruptLog = fmt.Errorf("write-ahead log does not match the snapshot")
+
+var ErrCheckpointFailed = fmt.Errorf("vectors were added but the checkpoint failed")
+
+var ErrInvalidCompressionLevel = fmt.Errorf("compression level must be between gzip.HuffmanOnly and gzip.BestCompression")
+
+var ErrUnsupportedFAISSIndex = fmt.Errorf("unsupported faiss index")
+
+var ErrInvalidFAISSIndex = fmt.Errorf("invalid faiss index")
+
+var ErrInvalidCode = fmt.Errorf("code must be less than the number of clusters")
+
+var ErrNoCodes = fmt.Errorf("index does not store product quantization codes")
+
+var ErrIncompatibleIndex = fmt.Errorf("indexes do not share the same configuration and trained model")
+
+const (
+	OpNew    = "new"
+	OpTrain  = "train"
+	OpAdd    = "add"
+	OpSearch = "search"
+	OpLoad   = "load"
+	OpMerge  = "merge"
+	OpEncode = "encode"
+	OpDecode = "decode"
+)
+
+// IndexError reports which operation failed on which index, along with the
+// values that caused it. It wraps one of the sentinel errors above, so
+// errors.Is keeps working and errors.As exposes the details.
+type IndexError struct {
+	Op        string
+	IndexType IndexType
+	// Subspace and List locate the failing PQ subspace or IVF list. They are
+	// -1 when the error is not tied to one.
+	Subspace int
+	List     int
+	// Values holds the offending values keyed by name, e.g. "required" and
+	// "actual" for ErrInsufficientTrainingData.
+	Values map[string]int
+	Err    error
+}
+
+func newIndexError(op string, indexType IndexType, err error, keyValues ...any) *IndexError {
+	indexErr := &IndexError{
+		Op:        op,
+		IndexType: indexType,
+		Subspace:  -1,
+		List:      -1,
+		Err:       err,
+	}
+	if len(keyValues) > 0 {
+		indexErr.Values = make(map[string]int, len(keyValues)/2)
+		for i := 0; i+1 < len(keyValues); i += 2 {
+			indexErr.Values[keyValues[i].(string)] = keyValues[i+1].(int)
+		}
+	}
+	return indexErr
+}
+
+func (e *IndexError) withSubspace(subspace int) *IndexError {
+	e.Subspace = subspace
+	return e
+}
+
+func (e *IndexError) withList(list int) *IndexError {
+	e.List = list
+	return e
+}
+
+func (e *IndexError) Error() string {
+	var b strings.Builder
+	b.WriteString(string(e.IndexType))
+	b.WriteString(" ")
+	b.WriteString(e.Op)
+	if e.Subspace >= 0 {
+		fmt.Fprintf(&b, " (subspace %d)", e.Subspace)
+	}
+	if e.List >= 0 {
+		fmt.Fprintf(&b, " (list %d)", e.List)
+	}
+	b.WriteString(": ")
+	b.WriteString(e.Err.Error())
+	if len(e.Values) > 0 {
+		keys := make([]string, 0, len(e.Values))
+		for key := range e.Values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for i, key := range keys {
+			if i == 0 {
+				b.WriteString(" [")
+			} else {
+				b.WriteString(" ")
+			}
+			fmt.Fprintf(&b, "%s=%d", key, e.Values[key])
+		}
+		b.WriteString("]")
+	}
+	return b.String()
+}
+
+func (e *IndexError) Unwrap() error {
+	return e.Err
+}
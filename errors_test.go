@@ -0,0 +1,158 @@
+package vanadium_index
+
+import (
+	"errors"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestTrainingValidationErrors(t *testing.T) {
+	data := []float32{
+		0.1, 0.2, 0.3, 0.4,
+		0.5, 0.6, 0.7, 0.8,
+	}
+
+	tests := []struct {
+		name    string
+		builder IndexBuilder
+		err     error
+	}{
+		{"pq", AsPQ(2, 4), ErrInsufficientTrainingData},
+		{"ivf flat", AsIVFFlat(4), ErrInsufficientTrainingData},
+		{"ivf pq", AsIVFPQ(2, 2, 4), ErrInsufficientTrainingData},
+		{"refine", AsRefine(AsPQ(2, 4), 2), ErrInsufficientTrainingData},
+	}
+	for _, tt := range tests {
+		index, err := NewIndex(4, tt.builder)
+		if err != nil {
+			t.Fatalf("%s: Failed to create index: %v", tt.name, err)
+		}
+		err = index.Train(data)
+		if !errors.Is(err, tt.err) {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+
+	binaryIndex, _ := NewBinaryIndex(8, AsBinaryIVF(4))
+	err := binaryIndex.Train([]uint8{1, 2})
+	if !errors.Is(err, ErrInsufficientTrainingData) {
+		t.Fatalf("binary ivf: expected %v, got %v", ErrInsufficientTrainingData, err)
+	}
+}
+
+func TestBuilderValidationErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder IndexBuilder
+		err     error
+	}{
+		{"pq subspaces", AsPQ(3, 4), ErrInvalidNumSubspaces},
+		{"pq sample size", AsPQ(2, 4, WithPQTrainSampleSize(2)), ErrInvalidSampleSize},
+		{"pq iterations", AsPQ(2, 4, WithPQMaxIterations(0)), ErrInvalidNumIterations},
+		{"pq tolerance", AsPQ(2, 4, WithPQTolerance(0)), ErrInvalidTol},
+		{"ivf sample size", AsIVFFlat(4, WithIVFTrainSampleSize(2)), ErrInvalidSampleSize},
+		{"ivf pq sample size", AsIVFPQ(2, 2, 16, WithIVFTrainSampleSize(8)), ErrInvalidSampleSize},
+		{"ivf pq subspaces", AsIVFPQ(2, 3, 4), ErrInvalidNumSubspaces},
+		{"ivf pq options", AsIVFFlat(2, WithIVFPQIndex()), ErrInvalidPQOptions},
+		{"refine k factor", AsRefine(AsFlat(), 0), ErrInvalidKFactor},
+	}
+	for _, tt := range tests {
+		_, err := NewIndex(4, tt.builder)
+		if !errors.Is(err, tt.err) {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+
+	_, err := NewIndex(0, AsIVFFlat(2))
+	if !errors.Is(err, ErrInvalidNumFeatures) {
+		t.Fatalf("ivf features: expected %v, got %v", ErrInvalidNumFeatures, err)
+	}
+}
+
+func TestIndexErrorDetails(t *testing.T) {
+	index, err := NewIndex(4, AsPQ(2, 4))
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	err = index.Train([]float32{0.1, 0.2, 0.3, 0.4})
+
+	var indexErr *IndexError
+	if !errors.As(err, &indexErr) {
+		t.Fatalf("expected *IndexError, got %T", err)
+	}
+	if indexErr.Op != OpTrain || indexErr.IndexType != IndexTypePQ {
+		t.Fatalf("unexpected op or index type: %s %s", indexErr.Op, indexErr.IndexType)
+	}
+	if indexErr.Values["required"] != 4 || indexErr.Values["actual"] != 1 {
+		t.Fatalf("unexpected values: %v", indexErr.Values)
+	}
+	if indexErr.Subspace != -1 || indexErr.List != -1 {
+		t.Fatalf("unexpected location: subspace %d, list %d", indexErr.Subspace, indexErr.List)
+	}
+
+	_, _, err = index.Search([]float32{0.1, 0.2, 0.3}, 1)
+	if !errors.As(err, &indexErr) || !errors.Is(err, ErrInvalidDataLength) {
+		t.Fatalf("expected ErrInvalidDataLength, got %v", err)
+	}
+	if indexErr.Values["dataLength"] != 3 || indexErr.Values["numFeatures"] != 4 {
+		t.Fatalf("unexpected values: %v", indexErr.Values)
+	}
+}
+
+func TestIndexErrorWrap(t *testing.T) {
+	err := newIndexError(OpTrain, IndexTypeIVF, ErrEmptyData).withList(3)
+	expected := "ivf train (list 3): data is empty"
+	if err.Error() != expected {
+		t.Fatalf("expected %q, got %q", expected, err.Error())
+	}
+
+	inner := newIndexError(OpTrain, IndexTypePQ, ErrInsufficientTrainingData, "required", 4, "actual", 2).withSubspace(1)
+	outer := newIndexError(OpTrain, IndexTypeIVF, inner).withList(0)
+	expected = "ivf train (list 0): pq train (subspace 1): number of training vectors must be greater than or equal to the number of clusters [actual=2 required=4]"
+	if outer.Error() != expected {
+		t.Fatalf("expected %q, got %q", expected, outer.Error())
+	}
+	if !errors.Is(outer, ErrInsufficientTrainingData) {
+		t.Fatalf("expected wrapped error to match ErrInsufficientTrainingData")
+	}
+	if inner.Op != OpTrain || inner.IndexType != IndexTypePQ || inner.List != -1 {
+		t.Fatalf("expected the inner error to be left as is, got %+v", inner)
+	}
+}
+
+func TestIndexErrorIVFPQMessage(t *testing.T) {
+	numFeatures := 4
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 64*numFeatures)
+	for i := range data {
+		data[i] = rng.Float32()
+	}
+	index, _ := NewIndex(numFeatures, AsIVFPQ(2, 2, 4, WithIVFSeed(1)))
+	err := index.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+
+	err = index.Add([]float32{0.1, 0.2, 0.3})
+	expected := "ivf add: data length must be divisible by the number of features [dataLength=3 numFeatures=4]"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("expected %q, got %v", expected, err)
+	}
+
+	// A failure inside a list keeps the op and type of both the IVF index
+	// and the list's PQ index.
+	ivf := index.(*InvertedFileIndex[uint8, uint8])
+	ivf.indexes[1].(*ProductQuantizationIndex[uint8]).state.IsTrained = false
+	_, _, err = ivf.Encode(data)
+	expected = "ivf encode (list 1): pq encode: index is not trained"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("expected %q, got %v", expected, err)
+	}
+	var indexErr *IndexError
+	if !errors.As(err, &indexErr) || indexErr.IndexType != IndexTypeIVF || indexErr.List != 1 {
+		t.Fatalf("expected the outer IVF error, got %+v", indexErr)
+	}
+	if !errors.Is(err, ErrNotTrained) {
+		t.Fatalf("expected wrapped error to match ErrNotTrained")
+	}
+}
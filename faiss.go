@@ -0,0 +1,911 @@
+package vanadium_index
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+)
+
+// The FAISS binary format (faiss/impl/index_write.cpp) is little-endian. Each
+// index starts with a fourcc, followed by a header and index specific fields.
+// Vectors are stored as a uint64 element count followed by the elements.
+const (
+	faissFourccFlatL2      = "IxF2"
+	faissFourccFlatIP      = "IxFI"
+	faissFourccFlat        = "IxFl"
+	faissFourccPQ          = "IxPq"
+	faissFourccPQLegacy    = "IxPQ"
+	faissFourccIVFFlat     = "IwFl"
+	faissFourccIVFPQ       = "IwPQ"
+	faissFourccIVFPQLegacy = "IvPQ" // ids in the IVF header, no inverted lists
+	faissFourccArrayInv    = "ilar"
+	faissFourccFull        = "full"
+	faissFourccSparse      = "sprs"
+
+	faissMetricL2 = 1
+
+	// faissDirectMapHashtable is the DirectMap type that is followed by the
+	// id pairs of the hashtable.
+	faissDirectMapHashtable = 2
+)
+
+// faissChunkSize bounds the allocation made for a single read, so that a
+// corrupt element count fails on a short read instead of allocating it.
+const faissChunkSize = 1 << 16
+
+// faissPQModel is implemented by every ProductQuantizationIndex
+// instantiation and gives FAISS import and export access to its model.
+type faissPQModel interface {
+	ANNIndex
+	faissCodebooks() [][][]float32
+	faissCodes() []int
+	setFAISSModel(codebooks [][][]float32, codes []int) error
+}
+
+// faissIVFModel is implemented by every InvertedFileIndex instantiation.
+type faissIVFModel interface {
+	ANNIndex
+	faissCentroids() [][]float32
+	faissLists() ([]ANNIndex, [][]int)
+	setFAISSModel(centroids [][]float32, mapping [][]int) error
+}
+
+// ReadFAISSIndex reads an index written by faiss.write_index. IndexFlatL2,
+// IndexPQ, IndexIVFFlat and IndexIVFPQ are supported and load into FlatIndex,
+// ProductQuantizationIndex and InvertedFileIndex. The IVF quantizer must be an
+// IndexFlatL2; the index searches a single list, as FAISS does with nprobe=1.
+func ReadFAISSIndex(r io.Reader) (ANNIndex, error) {
+	fr := &faissReader{r: bufio.NewReader(r)}
+	return fr.readIndex()
+}
+
+// WriteFAISSIndex writes index in the format read by faiss.read_index. FlatIndex,
+// ProductQuantizationIndex and InvertedFileIndex are supported. PQ indexes need
+// a power of two number of clusters. FAISS shares one PQ across the lists of an
+// IndexIVFPQ, so an IVF-PQ index is exported only if its lists share codebooks,
+// either as is or relative to their coarse centroids.
+func WriteFAISSIndex(w io.Writer, index ANNIndex) error {
+	bw := bufio.NewWriter(w)
+	fw := &faissWriter{w: bw}
+	err := fw.writeIndex(index)
+	if err == nil {
+		err = fw.err
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	return err
+}
+
+// LoadFAISSFile reads a FAISS index file written by faiss.write_index.
+func LoadFAISSFile(path string) (ANNIndex, error) {
+	var index ANNIndex
+	err := readFile(path, func(r io.Reader) error {
+		var err error
+		index, err = ReadFAISSIndex(r)
+		return err
+	})
+	return index, err
+}
+
+// SaveFAISSFile writes index to path atomically like SaveFile, in the format
+// read by faiss.read_index.
+func SaveFAISSFile(path string, index ANNIndex) error {
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return WriteFAISSIndex(w, index)
+	})
+}
+
+type faissHeader struct {
+	numFeatures int
+	numVectors  int
+	isTrained   bool
+}
+
+type faissPQ struct {
+	numFeatures  int
+	numSubspaces int
+	nbits        int
+	codebooks    [][][]float32
+}
+
+func (pq *faissPQ) codeSize() int {
+	return (pq.numSubspaces*pq.nbits + 7) / 8
+}
+
+type faissReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *faissReader) read(v any) {
+	if r.err == nil {
+		r.err = binary.Read(r.r, binary.LittleEndian, v)
+	}
+}
+
+func (r *faissReader) fourcc() string {
+	var b [4]byte
+	r.read(&b)
+	return string(b[:])
+}
+
+func (r *faissReader) int32() int {
+	var v int32
+	r.read(&v)
+	return int(v)
+}
+
+func (r *faissReader) int64() int {
+	var v int64
+	r.read(&v)
+	return int(v)
+}
+
+func (r *faissReader) size() int {
+	var v uint64
+	r.read(&v)
+	if r.err == nil && v > math.MaxInt32*uint64(faissChunkSize) {
+		r.err = fmt.Errorf("%w: size %d", ErrInvalidFAISSIndex, v)
+	}
+	return int(v)
+}
+
+func (r *faissReader) bool() bool {
+	var v uint8
+	r.read(&v)
+	return v != 0
+}
+
+// readFAISSSlice reads n elements in chunks of faissChunkSize.
+func readFAISSSlice[E any](r *faissReader, n int) []E {
+	s := make([]E, 0, min(n, faissChunkSize))
+	for len(s) < n && r.err == nil {
+		chunk := make([]E, min(n-len(s), faissChunkSize))
+		r.read(chunk)
+		s = append(s, chunk...)
+	}
+	return s
+}
+
+// readFAISSVector reads a vector stored as its length followed by its elements.
+func readFAISSVector[E any](r *faissReader) []E {
+	return readFAISSSlice[E](r, r.size())
+}
+
+func (r *faissReader) readIndex() (ANNIndex, error) {
+	fourcc := r.fourcc()
+	if r.err != nil {
+		return nil, r.err
+	}
+	switch fourcc {
+	case faissFourccFlatL2:
+		return r.readFlat()
+	case faissFourccPQ, faissFourccPQLegacy:
+		return r.readPQ(fourcc == faissFourccPQ)
+	case faissFourccIVFFlat:
+		return r.readIVF(false, false)
+	case faissFourccIVFPQ:
+		return r.readIVF(true, false)
+	case faissFourccIVFPQLegacy:
+		return r.readIVF(true, true)
+	case faissFourccFlatIP, faissFourccFlat:
+		return nil, fmt.Errorf("%w: %s uses a metric other than L2", ErrUnsupportedFAISSIndex, fourcc)
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnsupportedFAISSIndex, fourcc)
+}
+
+func (r *faissReader) readHeader() (faissHeader, error) {
+	header := faissHeader{}
+	header.numFeatures = r.int32()
+	header.numVectors = r.int64()
+	r.int64() // dummy
+	r.int64() // dummy
+	header.isTrained = r.bool()
+	metric := r.int32()
+	if r.err != nil {
+		return header, r.err
+	}
+	if metric != faissMetricL2 {
+		return header, fmt.Errorf("%w: metric type %d is not L2", ErrUnsupportedFAISSIndex, metric)
+	}
+	if header.numFeatures <= 0 || header.numVectors < 0 {
+		return header, fmt.Errorf("%w: d=%d ntotal=%d", ErrInvalidFAISSIndex, header.numFeatures, header.numVectors)
+	}
+	return header, nil
+}
+
+func (r *faissReader) readFlatData() (faissHeader, []float32, error) {
+	header, err := r.readHeader()
+	if err != nil {
+		return header, nil, err
+	}
+	data := readFAISSVector[float32](r)
+	if r.err != nil {
+		return header, nil, r.err
+	}
+	if len(data) != header.numVectors*header.numFeatures {
+		return header, nil, fmt.Errorf("%w: flat index has %d values for %d vectors", ErrInvalidFAISSIndex, len(data), header.numVectors)
+	}
+	return header, data, nil
+}
+
+func (r *faissReader) readFlat() (ANNIndex, error) {
+	header, data, err := r.readFlatData()
+	if err != nil {
+		return nil, err
+	}
+	index, err := newFlatIndex(header.numFeatures)
+	if err != nil {
+		return nil, err
+	}
+	index.state.Data = data
+	return index, nil
+}
+
+func (r *faissReader) readProductQuantizer() (*faissPQ, error) {
+	pq := &faissPQ{
+		numFeatures:  r.size(),
+		numSubspaces: r.size(),
+		nbits:        r.size(),
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if pq.numSubspaces <= 0 || pq.numFeatures%pq.numSubspaces != 0 || pq.nbits <= 0 || pq.nbits > 16 {
+		return nil, fmt.Errorf("%w: pq d=%d M=%d nbits=%d", ErrInvalidFAISSIndex, pq.numFeatures, pq.numSubspaces, pq.nbits)
+	}
+	numSubFeatures := pq.numFeatures / pq.numSubspaces
+	numClusters := 1 << pq.nbits
+	centroids := readFAISSVector[float32](r)
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(centroids) != pq.numSubspaces*numClusters*numSubFeatures {
+		return nil, fmt.Errorf("%w: pq has %d centroid values", ErrInvalidFAISSIndex, len(centroids))
+	}
+
+	pq.codebooks = make([][][]float32, pq.numSubspaces)
+	for m := range pq.numSubspaces {
+		pq.codebooks[m] = make([][]float32, numClusters)
+		for c := range numClusters {
+			start := (m*numClusters + c) * numSubFeatures
+			pq.codebooks[m][c] = centroids[start : start+numSubFeatures]
+		}
+	}
+	return pq, nil
+}
+
+func (r *faissReader) readPQ(hasSearchParams bool) (ANNIndex, error) {
+	header, err := r.readHeader()
+	if err != nil {
+		return nil, err
+	}
+	pq, err := r.readProductQuantizer()
+	if err != nil {
+		return nil, err
+	}
+	if pq.numFeatures != header.numFeatures {
+		return nil, fmt.Errorf("%w: pq has %d features, index has %d", ErrInvalidFAISSIndex, pq.numFeatures, header.numFeatures)
+	}
+	packed := readFAISSVector[uint8](r)
+	if hasSearchParams {
+		r.int32() // search_type
+		r.bool()  // encode_signs
+		r.int32() // polysemous_ht
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(packed) != header.numVectors*pq.codeSize() {
+		return nil, fmt.Errorf("%w: pq index has %d code bytes for %d vectors", ErrInvalidFAISSIndex, len(packed), header.numVectors)
+	}
+
+	index, err := NewIndex(header.numFeatures, AsPQ(pq.numSubspaces, 1<<pq.nbits))
+	if err != nil {
+		return nil, err
+	}
+	codes := unpackFAISSCodes(packed, pq.codeSize(), pq.numSubspaces, pq.nbits)
+	err = index.(faissPQModel).setFAISSModel(pq.codebooks, codes)
+	if err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func (r *faissReader) readIVF(isPQ bool, isLegacy bool) (ANNIndex, error) {
+	header, err := r.readHeader()
+	if err != nil {
+		return nil, err
+	}
+	numLists := r.size()
+	r.size() // nprobe
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	quantizerFourcc := r.fourcc()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if quantizerFourcc != faissFourccFlatL2 {
+		return nil, fmt.Errorf("%w: quantizer %q", ErrUnsupportedFAISSIndex, quantizerFourcc)
+	}
+	quantizer, flatCentroids, err := r.readFlatData()
+	if err != nil {
+		return nil, err
+	}
+	if quantizer.numFeatures != header.numFeatures || quantizer.numVectors != numLists || numLists == 0 {
+		return nil, fmt.Errorf("%w: quantizer has %d centroids for %d lists", ErrInvalidFAISSIndex, quantizer.numVectors, numLists)
+	}
+	centroids := make([][]float32, numLists)
+	for c := range numLists {
+		centroids[c] = flatCentroids[c*header.numFeatures : (c+1)*header.numFeatures]
+	}
+
+	var legacyIDs [][]int64
+	if isLegacy {
+		legacyIDs = make([][]int64, numLists)
+		for c := range numLists {
+			legacyIDs[c] = readFAISSVector[int64](r)
+		}
+	}
+	r.skipDirectMap()
+
+	var pq *faissPQ
+	byResidual := false
+	codeSize := header.numFeatures * 4
+	if isPQ {
+		byResidual = r.bool()
+		codeSize = r.size()
+		pq, err = r.readProductQuantizer()
+		if err != nil {
+			return nil, err
+		}
+		if pq.numFeatures != header.numFeatures || pq.codeSize() != codeSize {
+			return nil, fmt.Errorf("%w: pq code size %d does not match %d", ErrInvalidFAISSIndex, pq.codeSize(), codeSize)
+		}
+	}
+
+	var listCodes [][]byte
+	var mapping [][]int
+	if isLegacy {
+		listCodes, mapping, err = r.readLegacyLists(legacyIDs, codeSize, header.numVectors)
+	} else {
+		listCodes, mapping, err = r.readInvertedLists(numLists, codeSize, header.numVectors)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var builder IndexBuilder
+	if isPQ {
+		builder = AsIVFPQ(numLists, pq.numSubspaces, 1<<pq.nbits)
+	} else {
+		builder = AsIVFFlat(numLists)
+	}
+	index, err := NewIndex(header.numFeatures, builder)
+	if err != nil {
+		return nil, err
+	}
+	model := index.(faissIVFModel)
+	err = model.setFAISSModel(centroids, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	lists, _ := model.faissLists()
+	for c, codes := range listCodes {
+		if !isPQ {
+			data := make([]float32, len(codes)/4)
+			for i := range data {
+				data[i] = math.Float32frombits(binary.LittleEndian.Uint32(codes[i*4:]))
+			}
+			lists[c].(*FlatIndex).state.Data = data
+			continue
+		}
+
+		// FAISS encodes the residual from the coarse centroid. Adding the
+		// centroid to the codebooks gives per-list codebooks over the vectors
+		// themselves, with the same reconstructions and distances.
+		codebooks := pq.codebooks
+		if byResidual {
+			codebooks = residualCodebooks(pq.codebooks, centroids[c], 1)
+		}
+		err = lists[c].(faissPQModel).setFAISSModel(codebooks, unpackFAISSCodes(codes, codeSize, pq.numSubspaces, pq.nbits))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if index.NumVectors() != header.numVectors {
+		return nil, fmt.Errorf("%w: lists hold %d vectors, header has %d", ErrInvalidFAISSIndex, index.NumVectors(), header.numVectors)
+	}
+	return index, nil
+}
+
+func (r *faissReader) skipDirectMap() {
+	var directMapType uint8
+	r.read(&directMapType)
+	readFAISSVector[int64](r)
+	if directMapType == faissDirectMapHashtable {
+		pairs := r.size()
+		readFAISSSlice[int64](r, 2*pairs)
+	}
+}
+
+func (r *faissReader) readInvertedLists(numLists, codeSize, numVectors int) ([][]byte, [][]int, error) {
+	fourcc := r.fourcc()
+	if r.err != nil {
+		return nil, nil, r.err
+	}
+	if fourcc != faissFourccArrayInv {
+		return nil, nil, fmt.Errorf("%w: inverted lists %q", ErrUnsupportedFAISSIndex, fourcc)
+	}
+	if n, size := r.size(), r.size(); r.err == nil && (n != numLists || size != codeSize) {
+		return nil, nil, fmt.Errorf("%w: inverted lists have %d lists of code size %d", ErrInvalidFAISSIndex, n, size)
+	}
+
+	listType := r.fourcc()
+	sizes := readFAISSVector[uint64](r)
+	if r.err != nil {
+		return nil, nil, r.err
+	}
+	listSizes := make([]int, numLists)
+	var err error
+	switch listType {
+	case faissFourccFull:
+		if len(sizes) != numLists {
+			return nil, nil, fmt.Errorf("%w: %d list sizes for %d lists", ErrInvalidFAISSIndex, len(sizes), numLists)
+		}
+		for c, size := range sizes {
+			listSizes[c], err = faissListSize(size, codeSize)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	case faissFourccSparse:
+		if len(sizes)%2 != 0 {
+			return nil, nil, fmt.Errorf("%w: odd sparse list sizes", ErrInvalidFAISSIndex)
+		}
+		for i := 0; i < len(sizes); i += 2 {
+			if sizes[i] >= uint64(numLists) {
+				return nil, nil, fmt.Errorf("%w: list %d out of range", ErrInvalidFAISSIndex, sizes[i])
+			}
+			listSizes[sizes[i]], err = faissListSize(sizes[i+1], codeSize)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	default:
+		return nil, nil, fmt.Errorf("%w: list type %q", ErrUnsupportedFAISSIndex, listType)
+	}
+
+	codes := make([][]byte, numLists)
+	mapping := make([][]int, numLists)
+	for c, size := range listSizes {
+		codes[c] = readFAISSSlice[byte](r, size*codeSize)
+		ids := readFAISSSlice[int64](r, size)
+		if r.err != nil {
+			return nil, nil, r.err
+		}
+		mapping[c], err = faissMapping(ids, numVectors)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return codes, mapping, nil
+}
+
+// faissListSize converts a list size read from the file, bounding the codes
+// of the list like size bounds a vector.
+func faissListSize(size uint64, codeSize int) (int, error) {
+	if size > math.MaxInt32*uint64(faissChunkSize)/uint64(codeSize) {
+		return 0, fmt.Errorf("%w: list size %d", ErrInvalidFAISSIndex, size)
+	}
+	return int(size), nil
+}
+
+// faissMapping converts the ids of a list, which must be positions below the
+// number of vectors in the index.
+func faissMapping(ids []int64, numVectors int) ([]int, error) {
+	mapping := make([]int, len(ids))
+	for i, id := range ids {
+		if id < 0 || id >= int64(numVectors) {
+			return nil, fmt.Errorf("%w: id %d out of range for %d vectors", ErrInvalidFAISSIndex, id, numVectors)
+		}
+		mapping[i] = int(id)
+	}
+	return mapping, nil
+}
+
+// readLegacyLists reads the codes that the legacy IVF layouts store after the
+// index fields, one vector per list, for the ids read from the IVF header.
+func (r *faissReader) readLegacyLists(ids [][]int64, codeSize, numVectors int) ([][]byte, [][]int, error) {
+	codes := make([][]byte, len(ids))
+	mapping := make([][]int, len(ids))
+	for c := range ids {
+		codes[c] = readFAISSVector[byte](r)
+		if r.err != nil {
+			return nil, nil, r.err
+		}
+		if len(codes[c]) != len(ids[c])*codeSize {
+			return nil, nil, fmt.Errorf("%w: list %d has %d code bytes for %d ids", ErrInvalidFAISSIndex, c, len(codes[c]), len(ids[c]))
+		}
+		var err error
+		mapping[c], err = faissMapping(ids[c], numVectors)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return codes, mapping, nil
+}
+
+type faissWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *faissWriter) write(v any) {
+	if w.err == nil {
+		w.err = binary.Write(w.w, binary.LittleEndian, v)
+	}
+}
+
+func (w *faissWriter) fourcc(s string) {
+	w.write([]byte(s))
+}
+
+func (w *faissWriter) int32(v int) {
+	w.write(int32(v))
+}
+
+func (w *faissWriter) int64(v int) {
+	w.write(int64(v))
+}
+
+func (w *faissWriter) size(v int) {
+	w.write(uint64(v))
+}
+
+func (w *faissWriter) bool(v bool) {
+	if v {
+		w.write(uint8(1))
+	} else {
+		w.write(uint8(0))
+	}
+}
+
+func (w *faissWriter) writeHeader(header faissHeader) {
+	w.int32(header.numFeatures)
+	w.int64(header.numVectors)
+	w.int64(1 << 20) // dummy
+	w.int64(1 << 20) // dummy
+	w.bool(header.isTrained)
+	w.int32(faissMetricL2)
+}
+
+func (w *faissWriter) writeIndex(index ANNIndex) error {
+	switch index := index.(type) {
+	case *FlatIndex:
+		w.writeFlat(index.state.NumFeatures, index.NumVectors(), index.state.Data)
+		return nil
+	case *DurableIndex:
+		return w.writeIndex(index.ANNIndex)
+	case faissPQModel:
+		return w.writePQ(index)
+	case faissIVFModel:
+		return w.writeIVF(index)
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupportedFAISSIndex, index.Info().IndexType)
+}
+
+func (w *faissWriter) writeFlat(numFeatures, numVectors int, data []float32) {
+	w.fourcc(faissFourccFlatL2)
+	w.writeHeader(faissHeader{numFeatures: numFeatures, numVectors: numVectors, isTrained: true})
+	w.size(numVectors * numFeatures)
+	w.write(data[:numVectors*numFeatures])
+}
+
+func (w *faissWriter) writePQ(index faissPQModel) error {
+	info := index.Info()
+	pq, err := newFAISSPQ(info, index.faissCodebooks())
+	if err != nil {
+		return err
+	}
+	packed := packFAISSCodes(index.faissCodes(), pq.codeSize(), pq.numSubspaces, pq.nbits)
+
+	w.fourcc(faissFourccPQ)
+	w.writeHeader(faissHeader{numFeatures: info.NumFeatures, numVectors: info.NumVectors, isTrained: true})
+	w.writeProductQuantizer(pq)
+	w.size(len(packed))
+	w.write(packed)
+	w.int32(0)    // search_type: ST_PQ
+	w.bool(false) // encode_signs
+	w.int32(0)    // polysemous_ht
+	return nil
+}
+
+func newFAISSPQ(info IndexInfo, codebooks [][][]float32) (*faissPQ, error) {
+	if !info.IsTrained {
+		return nil, ErrNotTrained
+	}
+	if bits.OnesCount(uint(info.NumClusters)) != 1 {
+		return nil, fmt.Errorf("%w: %d clusters is not a power of two", ErrUnsupportedFAISSIndex, info.NumClusters)
+	}
+	return &faissPQ{
+		numFeatures:  info.NumFeatures,
+		numSubspaces: info.NumSubspaces,
+		nbits:        bits.TrailingZeros(uint(info.NumClusters)),
+		codebooks:    codebooks,
+	}, nil
+}
+
+func (w *faissWriter) writeProductQuantizer(pq *faissPQ) {
+	w.size(pq.numFeatures)
+	w.size(pq.numSubspaces)
+	w.size(pq.nbits)
+	numClusters := 1 << pq.nbits
+	w.size(pq.numFeatures * numClusters)
+	for _, codebook := range pq.codebooks {
+		for _, centroid := range codebook {
+			w.write(centroid)
+		}
+	}
+}
+
+func (w *faissWriter) writeIVF(index faissIVFModel) error {
+	info := index.Info()
+	if !info.IsTrained {
+		return ErrNotTrained
+	}
+	centroids := index.faissCentroids()
+	lists, mapping := index.faissLists()
+
+	var pq *faissPQ
+	byResidual := false
+	codeSize := info.NumFeatures * 4
+	_, isFlat := lists[0].(*FlatIndex)
+	if !isFlat {
+		codebooks, residual, err := sharedFAISSCodebooks(lists, centroids)
+		if err != nil {
+			return err
+		}
+		pq, err = newFAISSPQ(*info.Sub, codebooks)
+		if err != nil {
+			return err
+		}
+		byResidual = residual
+		codeSize = pq.codeSize()
+	}
+
+	if isFlat {
+		w.fourcc(faissFourccIVFFlat)
+	} else {
+		w.fourcc(faissFourccIVFPQ)
+	}
+	w.writeHeader(faissHeader{numFeatures: info.NumFeatures, numVectors: info.NumVectors, isTrained: true})
+	w.size(len(centroids))
+	w.size(1) // nprobe
+	flatCentroids := make([]float32, 0, len(centroids)*info.NumFeatures)
+	for _, centroid := range centroids {
+		flatCentroids = append(flatCentroids, centroid...)
+	}
+	w.writeFlat(info.NumFeatures, len(centroids), flatCentroids)
+	w.write(uint8(0)) // direct map type: NoMap
+	w.size(0)         // direct map array
+	if !isFlat {
+		w.bool(byResidual)
+		w.size(codeSize)
+		w.writeProductQuantizer(pq)
+	}
+
+	numNonEmpty := 0
+	for _, ids := range mapping {
+		if len(ids) > 0 {
+			numNonEmpty++
+		}
+	}
+	w.fourcc(faissFourccArrayInv)
+	w.size(len(lists))
+	w.size(codeSize)
+	if numNonEmpty > len(lists)/2 {
+		w.fourcc(faissFourccFull)
+		w.size(len(lists))
+		for _, ids := range mapping {
+			w.size(len(ids))
+		}
+	} else {
+		w.fourcc(faissFourccSparse)
+		w.size(2 * numNonEmpty)
+		for c, ids := range mapping {
+			if len(ids) > 0 {
+				w.size(c)
+				w.size(len(ids))
+			}
+		}
+	}
+	for c, ids := range mapping {
+		if len(ids) == 0 {
+			continue
+		}
+		if isFlat {
+			w.write(lists[c].(*FlatIndex).state.Data[:len(ids)*info.NumFeatures])
+		} else {
+			w.write(packFAISSCodes(lists[c].(faissPQModel).faissCodes(), codeSize, pq.numSubspaces, pq.nbits))
+		}
+		faissIDs := make([]int64, len(ids))
+		for i, id := range ids {
+			faissIDs[i] = int64(id)
+		}
+		w.write(faissIDs)
+	}
+	return nil
+}
+
+// sharedFAISSCodebooks returns the codebooks shared by all lists of an IVF-PQ
+// index. Lists that were trained independently have no shared codebooks and
+// cannot be exported.
+func sharedFAISSCodebooks(lists []ANNIndex, centroids [][]float32) ([][][]float32, bool, error) {
+	models := make([]faissPQModel, len(lists))
+	for c, list := range lists {
+		models[c] = list.(faissPQModel)
+	}
+
+	codebooks := models[0].faissCodebooks()
+	isShared := true
+	for _, model := range models[1:] {
+		if !equalCodebooks(codebooks, model.faissCodebooks(), 0) {
+			isShared = false
+			break
+		}
+	}
+	if isShared {
+		return codebooks, false, nil
+	}
+
+	residuals := residualCodebooks(codebooks, centroids[0], -1)
+	for c, model := range models[1:] {
+		listResiduals := residualCodebooks(model.faissCodebooks(), centroids[c+1], -1)
+		if !equalCodebooks(residuals, listResiduals, 1e-5) {
+			return nil, false, fmt.Errorf("%w: lists of the IVF-PQ index do not share codebooks", ErrUnsupportedFAISSIndex)
+		}
+	}
+	return residuals, true, nil
+}
+
+// residualCodebooks adds sign times centroid to each codeword.
+func residualCodebooks(codebooks [][][]float32, centroid []float32, sign float32) [][][]float32 {
+	residuals := make([][][]float32, len(codebooks))
+	for m, codebook := range codebooks {
+		residuals[m] = make([][]float32, len(codebook))
+		for c, codeword := range codebook {
+			start := m * len(codeword)
+			residuals[m][c] = make([]float32, len(codeword))
+			for i, v := range codeword {
+				residuals[m][c][i] = v + sign*centroid[start+i]
+			}
+		}
+	}
+	return residuals
+}
+
+// equalCodebooks compares codebooks with a tolerance relative to the
+// magnitude of each value.
+func equalCodebooks(x, y [][][]float32, tolerance float32) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for m := range x {
+		if len(x[m]) != len(y[m]) {
+			return false
+		}
+		for c := range x[m] {
+			if len(x[m][c]) != len(y[m][c]) {
+				return false
+			}
+			for i := range x[m][c] {
+				a, b := x[m][c][i], y[m][c][i]
+				scale := 1 + max(abs32(a), abs32(b))
+				if abs32(a-b) > tolerance*scale {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func abs32(x float32) float32 {
+	return math.Float32frombits(math.Float32bits(x) &^ (1 << 31))
+}
+
+// unpackFAISSCodes reads codes of nbits bits each, packed least significant
+// bit first into codeSize bytes per vector.
+func unpackFAISSCodes(packed []byte, codeSize, numSubspaces, nbits int) []int {
+	numVectors := len(packed) / codeSize
+	codes := make([]int, numVectors*numSubspaces)
+	for n := range numVectors {
+		vector := packed[n*codeSize : (n+1)*codeSize]
+		offset := 0
+		for m := range numSubspaces {
+			code := 0
+			for b := range nbits {
+				bit := offset + b
+				code |= int(vector[bit/8]>>(bit%8)&1) << b
+			}
+			codes[n*numSubspaces+m] = code
+			offset += nbits
+		}
+	}
+	return codes
+}
+
+// packFAISSCodes is the inverse of unpackFAISSCodes.
+func packFAISSCodes(codes []int, codeSize, numSubspaces, nbits int) []byte {
+	numVectors := len(codes) / numSubspaces
+	packed := make([]byte, numVectors*codeSize)
+	for n := range numVectors {
+		vector := packed[n*codeSize : (n+1)*codeSize]
+		offset := 0
+		for m := range numSubspaces {
+			code := codes[n*numSubspaces+m]
+			for b := range nbits {
+				bit := offset + b
+				vector[bit/8] |= byte(code>>b&1) << (bit % 8)
+			}
+			offset += nbits
+		}
+	}
+	return packed
+}
+
+func (index *ProductQuantizationIndex[T]) faissCodebooks() [][][]float32 {
+	return index.state.Codebooks
+}
+
+func (index *ProductQuantizationIndex[T]) faissCodes() []int {
+	numCodes := index.state.NumVectors * index.state.NumSubspaces
+	codes := make([]int, numCodes)
+	for i, code := range index.state.Codes[:numCodes] {
+		codes[i] = int(code)
+	}
+	return codes
+}
+
+func (index *ProductQuantizationIndex[T]) setFAISSModel(codebooks [][][]float32, codes []int) error {
+	for i := range index.state.NumSubspaces {
+		err := setCentroids(index.clusters[i], codebooks[i])
+		if err != nil {
+			return err
+		}
+		index.state.Codebooks[i] = codebooks[i]
+	}
+	index.state.Codes = make([]T, len(codes))
+	for i, code := range codes {
+		index.state.Codes[i] = T(code)
+	}
+	index.state.NumVectors = len(codes) / index.state.NumSubspaces
+	index.state.IsTrained = true
+	return nil
+}
+
+func (index *InvertedFileIndex[T1, T2]) faissCentroids() [][]float32 {
+	return index.cluster.Centroids()
+}
+
+func (index *InvertedFileIndex[T1, T2]) faissLists() ([]ANNIndex, [][]int) {
+	return index.indexes, index.state.Mapping
+}
+
+func (index *InvertedFileIndex[T1, T2]) setFAISSModel(centroids [][]float32, mapping [][]int) error {
+	err := setCentroids(index.cluster, centroids)
+	if err != nil {
+		return err
+	}
+	index.state.Mapping = mapping
+	index.state.IsTrained = true
+	return nil
+}
@@ -0,0 +1,347 @@
+package vanadium_index
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// faissBytes lays out fields the way faiss::write_index does, so that the
+// fixtures below spell out the FAISS format independently of faiss.go.
+func faissBytes(t *testing.T, fields ...any) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	for _, field := range fields {
+		if s, ok := field.(string); ok {
+			field = []byte(s)
+		}
+		err := binary.Write(&buf, binary.LittleEndian, field)
+		if err != nil {
+			t.Fatalf("Failed to write fixture field %v: %v", field, err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// faissHeaderFields returns the fields of write_index_header for an L2 index.
+func faissHeaderFields(d int32, ntotal int64) []any {
+	return []any{d, ntotal, int64(1 << 20), int64(1 << 20), uint8(1), int32(1)}
+}
+
+func TestReadFAISSIndexFlat(t *testing.T) {
+	fields := []any{"IxF2"}
+	fields = append(fields, faissHeaderFields(2, 3)...)
+	fields = append(fields, uint64(6), []float32{0, 0, 1, 1, 5, 5})
+	fixture := faissBytes(t, fields...)
+
+	index, err := ReadFAISSIndex(bytes.NewReader(fixture))
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if _, ok := index.(*FlatIndex); !ok {
+		t.Fatalf("expected FlatIndex, got %T", index)
+	}
+	results, distances, err := index.Search([]float32{1, 1.5}, 2)
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if !reflect.DeepEqual(results, [][]int{{1, 0}}) || !reflect.DeepEqual(distances, [][]float32{{0.25, 3.25}}) {
+		t.Fatalf("unexpected results %v %v", results, distances)
+	}
+
+	var buf bytes.Buffer
+	err = WriteFAISSIndex(&buf, index)
+	if err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), fixture) {
+		t.Fatalf("written index differs from fixture")
+	}
+}
+
+func TestReadFAISSIndexIVFPQByResidual(t *testing.T) {
+	// Two lists centered at (0, 0) and (10, 10), and a residual PQ with two
+	// one-dimensional subspaces of two codewords, -1 and 1.
+	fields := []any{"IwPQ"}
+	fields = append(fields, faissHeaderFields(2, 3)...)
+	fields = append(fields, uint64(2), uint64(1), "IxF2")
+	fields = append(fields, faissHeaderFields(2, 2)...)
+	fields = append(fields,
+		uint64(4), []float32{0, 0, 10, 10},
+		uint8(0), uint64(0), // direct map
+		uint8(1), uint64(1), // by_residual, code_size
+		uint64(2), uint64(2), uint64(1), uint64(4), []float32{-1, 1, -1, 1},
+		"ilar", uint64(2), uint64(1), "full", uint64(2), []uint64{2, 1},
+		[]uint8{0b10, 0b11}, []int64{0, 1}, // (-1, 1), (1, 1)
+		[]uint8{0b00}, []int64{2}, // (9, 9)
+	)
+	fixture := faissBytes(t, fields...)
+
+	index, err := ReadFAISSIndex(bytes.NewReader(fixture))
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if index.NumVectors() != 3 {
+		t.Fatalf("expected 3 vectors, got %d", index.NumVectors())
+	}
+	results, distances, err := index.Search([]float32{1, 1, 9, 9.5}, 2)
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if !reflect.DeepEqual(results, [][]int{{1, 0}, {2}}) || !reflect.DeepEqual(distances, [][]float32{{0, 4}, {0.25}}) {
+		t.Fatalf("unexpected results %v %v", results, distances)
+	}
+
+	var buf bytes.Buffer
+	err = WriteFAISSIndex(&buf, index)
+	if err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), fixture) {
+		t.Fatalf("written index differs from fixture")
+	}
+}
+
+func TestReadFAISSIndexInvalidLists(t *testing.T) {
+	fixture := func(sizes []uint64, ids []int64) []byte {
+		fields := []any{"IwFl"}
+		fields = append(fields, faissHeaderFields(1, 2)...)
+		fields = append(fields, uint64(1), uint64(1), "IxF2")
+		fields = append(fields, faissHeaderFields(1, 1)...)
+		fields = append(fields,
+			uint64(1), []float32{0},
+			uint8(0), uint64(0), // direct map
+			"ilar", uint64(1), uint64(4), "full", uint64(1), sizes,
+			[]float32{1, 2}, ids,
+		)
+		return faissBytes(t, fields...)
+	}
+
+	_, err := ReadFAISSIndex(bytes.NewReader(fixture([]uint64{2}, []int64{0, 1})))
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	tests := map[string][]byte{
+		"list size":       fixture([]uint64{math.MaxUint64}, []int64{0, 1}),
+		"negative id":     fixture([]uint64{2}, []int64{0, -1}),
+		"id out of range": fixture([]uint64{2}, []int64{0, 2}),
+	}
+	for name, b := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ReadFAISSIndex(bytes.NewReader(b))
+			if !errors.Is(err, ErrInvalidFAISSIndex) {
+				t.Fatalf("expected %v, got %v", ErrInvalidFAISSIndex, err)
+			}
+		})
+	}
+}
+
+func TestReadFAISSIndexIVFPQLegacy(t *testing.T) {
+	// The index of TestReadFAISSIndexIVFPQByResidual in the layout of old
+	// FAISS versions, with the ids in the IVF header and no inverted lists.
+	fields := []any{"IvPQ"}
+	fields = append(fields, faissHeaderFields(2, 3)...)
+	fields = append(fields, uint64(2), uint64(1), "IxF2")
+	fields = append(fields, faissHeaderFields(2, 2)...)
+	fields = append(fields,
+		uint64(4), []float32{0, 0, 10, 10},
+		uint64(2), []int64{0, 1}, uint64(1), []int64{2}, // ids
+		uint8(0), uint64(0), // direct map
+		uint8(1), uint64(1), // by_residual, code_size
+		uint64(2), uint64(2), uint64(1), uint64(4), []float32{-1, 1, -1, 1},
+		uint64(2), []uint8{0b10, 0b11}, uint64(1), []uint8{0b00}, // codes
+	)
+	index, err := ReadFAISSIndex(bytes.NewReader(faissBytes(t, fields...)))
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	results, distances, err := index.Search([]float32{1, 1, 9, 9.5}, 2)
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if !reflect.DeepEqual(results, [][]int{{1, 0}, {2}}) || !reflect.DeepEqual(distances, [][]float32{{0, 4}, {0.25}}) {
+		t.Fatalf("unexpected results %v %v", results, distances)
+	}
+
+	// Codes that do not match the ids of their list are rejected.
+	fields[len(fields)-2] = uint64(2)
+	fields[len(fields)-1] = []uint8{0b00, 0b01}
+	_, err = ReadFAISSIndex(bytes.NewReader(faissBytes(t, fields...)))
+	if !errors.Is(err, ErrInvalidFAISSIndex) {
+		t.Fatalf("expected %v, got %v", ErrInvalidFAISSIndex, err)
+	}
+}
+
+// TestReadFAISSIndexFromFAISS reads the fixtures in testdata/faiss and
+// expects the search results recorded with them. generate.py writes them
+// with FAISS, write_fixtures.py without it.
+func TestReadFAISSIndexFromFAISS(t *testing.T) {
+	for _, name := range []string{"flat_l2", "pq", "ivf_flat", "ivf_pq"} {
+		t.Run(name, func(t *testing.T) {
+			expectedPath := filepath.Join("testdata", "faiss", name+".json")
+			b, err := os.ReadFile(expectedPath)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", expectedPath, err)
+			}
+			var expected struct {
+				K         int         `json:"k"`
+				Queries   [][]float32 `json:"queries"`
+				IDs       [][]int     `json:"ids"`
+				Distances [][]float32 `json:"distances"`
+			}
+			err = json.Unmarshal(b, &expected)
+			if err != nil {
+				t.Fatalf("Failed to decode %s: %v", expectedPath, err)
+			}
+
+			index, err := LoadFAISSFile(filepath.Join("testdata", "faiss", name+".faiss"))
+			if err != nil {
+				t.Fatalf("Failed to read index: %v", err)
+			}
+			query := []float32{}
+			for _, q := range expected.Queries {
+				query = append(query, q...)
+			}
+			results, distances, err := index.Search(query, expected.K)
+			if err != nil {
+				t.Fatalf("Failed to search: %v", err)
+			}
+			for q := range expected.IDs {
+				// FAISS pads results with -1 when a list holds fewer than k
+				// vectors.
+				ids := expected.IDs[q]
+				for i, id := range ids {
+					if id < 0 {
+						ids = ids[:i]
+						break
+					}
+				}
+				if !reflect.DeepEqual(results[q], ids) {
+					t.Fatalf("query %d: expected %v, got %v", q, ids, results[q])
+				}
+				for i := range ids {
+					want, got := expected.Distances[q][i], distances[q][i]
+					if math.Abs(float64(want-got)) > 1e-4*math.Max(1, math.Abs(float64(want))) {
+						t.Fatalf("query %d: expected distance %v, got %v", q, want, got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFAISSRoundTrip(t *testing.T) {
+	numFeatures := 4
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 300*numFeatures)
+	for i := range data {
+		data[i] = rng.Float32()
+	}
+	query := data[:10*numFeatures]
+
+	builders := map[string]IndexBuilder{
+		"Flat":     AsFlat(),
+		"PQ2x4":    AsPQ(2, 16, WithPQMaxIterations(5), WithPQSeed(1)),
+		"PQ2x8":    AsPQ(2, 256, WithPQMaxIterations(5), WithPQSeed(1)),
+		"IVF4Flat": AsIVFFlat(4, WithIVFMaxIterations(5), WithIVFSeed(1)),
+	}
+	for name, builder := range builders {
+		t.Run(name, func(t *testing.T) {
+			index, _ := NewIndex(numFeatures, builder)
+			err := index.Train(data)
+			if err != nil {
+				t.Fatalf("Failed to train index: %v", err)
+			}
+			err = index.Add(data)
+			if err != nil {
+				t.Fatalf("Failed to add data: %v", err)
+			}
+
+			path := filepath.Join(t.TempDir(), "index.faiss")
+			err = SaveFAISSFile(path, index)
+			if err != nil {
+				t.Fatalf("Failed to write index: %v", err)
+			}
+			loaded, err := LoadFAISSFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read index: %v", err)
+			}
+			if loaded.Describe() != index.Describe() {
+				t.Fatalf("expected %s, got %s", index.Describe(), loaded.Describe())
+			}
+
+			results, distances, _ := index.Search(query, 5)
+			loadedResults, loadedDistances, err := loaded.Search(query, 5)
+			if err != nil {
+				t.Fatalf("Failed to search: %v", err)
+			}
+			if !reflect.DeepEqual(results, loadedResults) || !reflect.DeepEqual(distances, loadedDistances) {
+				t.Fatalf("search results differ after round trip")
+			}
+		})
+	}
+}
+
+func TestFAISSErrors(t *testing.T) {
+	refine, _ := NewIndex(2, AsRefine(AsFlat(), 2))
+	err := WriteFAISSIndex(io.Discard, refine)
+	if !errors.Is(err, ErrUnsupportedFAISSIndex) {
+		t.Fatalf("expected %v, got %v", ErrUnsupportedFAISSIndex, err)
+	}
+
+	pq, _ := NewIndex(2, AsPQ(1, 3))
+	pq.Train([]float32{0, 0, 1, 1, 2, 2})
+	err = WriteFAISSIndex(io.Discard, pq)
+	if !errors.Is(err, ErrUnsupportedFAISSIndex) {
+		t.Fatalf("expected %v, got %v", ErrUnsupportedFAISSIndex, err)
+	}
+
+	untrained, _ := NewIndex(2, AsIVFFlat(2))
+	err = WriteFAISSIndex(io.Discard, untrained)
+	if !errors.Is(err, ErrNotTrained) {
+		t.Fatalf("expected %v, got %v", ErrNotTrained, err)
+	}
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 64*2)
+	for i := range data {
+		data[i] = rng.Float32()
+	}
+	ivfpq, _ := NewIndex(2, AsIVFPQ(2, 1, 4, WithIVFSeed(1)))
+	ivfpq.Train(data)
+	err = WriteFAISSIndex(io.Discard, ivfpq)
+	if !errors.Is(err, ErrUnsupportedFAISSIndex) {
+		t.Fatalf("expected %v, got %v", ErrUnsupportedFAISSIndex, err)
+	}
+
+	fields := []any{"IxFI"}
+	fields = append(fields, faissHeaderFields(2, 0)...)
+	_, err = ReadFAISSIndex(bytes.NewReader(faissBytes(t, fields...)))
+	if !errors.Is(err, ErrUnsupportedFAISSIndex) {
+		t.Fatalf("expected %v, got %v", ErrUnsupportedFAISSIndex, err)
+	}
+
+	fields = []any{"IxF2"}
+	fields = append(fields, faissHeaderFields(2, 3)...)
+	fields = append(fields, uint64(6), []float32{0, 0, 1})
+	_, err = ReadFAISSIndex(bytes.NewReader(faissBytes(t, fields...)))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected %v, got %v", io.ErrUnexpectedEOF, err)
+	}
+
+	fields = []any{"IxF2"}
+	fields = append(fields, faissHeaderFields(2, 3)...)
+	fields = append(fields, uint64(4), []float32{0, 0, 1, 1})
+	_, err = ReadFAISSIndex(bytes.NewReader(faissBytes(t, fields...)))
+	if !errors.Is(err, ErrInvalidFAISSIndex) {
+		t.Fatalf("expected %v, got %v", ErrInvalidFAISSIndex, err)
+	}
+}
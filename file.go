@@ -0,0 +1,214 @@
+package vanadium_index
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// gzipMagic starts every gzip stream. A gob stream cannot start with it, so
+// readers use it to detect compressed indexes.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// SaveFile writes index to path atomically: it writes a temporary file in
+// the same directory, syncs it and renames it over path, so that a crash
+// leaves either the previous file or the new one, never a partial index.
+func SaveFile(path string, index ANNIndex, opts ...SaveOption) error {
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return WriteIndex(w, index, opts...)
+	})
+}
+
+// LoadFile loads an index written by SaveFile or Save.
+func LoadFile(path string) (ANNIndex, error) {
+	var index ANNIndex
+	err := readFile(path, func(r io.Reader) error {
+		var err error
+		index, err = ReadIndex(r)
+		return err
+	})
+	return index, err
+}
+
+// SaveBinaryFile writes a binary index to path atomically like SaveFile.
+func SaveBinaryFile(path string, index BinaryANNIndex, opts ...SaveOption) error {
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return WriteBinaryIndex(w, index, opts...)
+	})
+}
+
+// LoadBinaryFile loads a binary index written by SaveBinaryFile or Save.
+func LoadBinaryFile(path string) (BinaryANNIndex, error) {
+	var index BinaryANNIndex
+	err := readFile(path, func(r io.Reader) error {
+		var err error
+		index, err = ReadBinaryIndex(r)
+		return err
+	})
+	return index, err
+}
+
+// WriteIndex saves index to w, compressed according to opts.
+func WriteIndex(w io.Writer, index ANNIndex, opts ...SaveOption) error {
+	return writeCompressed(w, opts, func(w io.Writer) error {
+		return index.Save(gob.NewEncoder(w))
+	})
+}
+
+// ReadIndex loads an index written by WriteIndex or Save, detecting whether
+// it is compressed.
+func ReadIndex(r io.Reader) (ANNIndex, error) {
+	var index ANNIndex
+	err := readCompressed(r, func(r io.Reader) error {
+		var err error
+		index, err = LoadIndex(gob.NewDecoder(r))
+		return err
+	})
+	return index, err
+}
+
+// WriteBinaryIndex saves a binary index to w like WriteIndex.
+func WriteBinaryIndex(w io.Writer, index BinaryANNIndex, opts ...SaveOption) error {
+	return writeCompressed(w, opts, func(w io.Writer) error {
+		return index.Save(gob.NewEncoder(w))
+	})
+}
+
+// ReadBinaryIndex loads a binary index written by WriteBinaryIndex or Save.
+func ReadBinaryIndex(r io.Reader) (BinaryANNIndex, error) {
+	var index BinaryANNIndex
+	err := readCompressed(r, func(r io.Reader) error {
+		var err error
+		index, err = LoadBinaryIndex(gob.NewDecoder(r))
+		return err
+	})
+	return index, err
+}
+
+func writeCompressed(w io.Writer, opts []SaveOption, write func(w io.Writer) error) error {
+	config := &SaveConfig{}
+	for _, opt := range opts {
+		err := opt(config)
+		if err != nil {
+			return err
+		}
+	}
+	if config.Compression == CompressionNone {
+		return write(w)
+	}
+
+	zw, err := gzip.NewWriterLevel(w, config.CompressionLevel)
+	if err != nil {
+		return err
+	}
+	err = write(zw)
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func readCompressed(r io.Reader, read func(r io.Reader) error) error {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	magic, err := br.Peek(len(gzipMagic))
+	if err != nil || !bytes.Equal(magic, gzipMagic) {
+		return read(br)
+	}
+
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	return read(zr)
+}
+
+// deltaEncodeMapping stores each id of a list as the difference to the
+// previous one. Ids are appended in increasing order, so the differences are
+// small and gob writes them in fewer bytes, which also compress better.
+func deltaEncodeMapping(mapping [][]int) [][]int {
+	deltas := make([][]int, len(mapping))
+	for c, ids := range mapping {
+		deltas[c] = make([]int, len(ids))
+		prev := 0
+		for i, id := range ids {
+			deltas[c][i] = id - prev
+			prev = id
+		}
+	}
+	return deltas
+}
+
+// deltaDecodeMapping reverses deltaEncodeMapping in place.
+func deltaDecodeMapping(deltas [][]int) {
+	for _, ids := range deltas {
+		for i := 1; i < len(ids); i++ {
+			ids[i] += ids[i-1]
+		}
+	}
+}
+
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	dir := filepath.Dir(path)
+	file, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	w := bufio.NewWriter(file)
+	err = write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = file.Chmod(0o644)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir makes a rename in dir durable. Windows cannot sync directories and
+// persists renames without it.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func readFile(path string, read func(r io.Reader) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return read(bufio.NewReader(file))
+}
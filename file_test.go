@@ -0,0 +1,160 @@
+package vanadium_index
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveFileLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index")
+	index, _ := NewIndex(2, AsFlat())
+	err := index.Add([]float32{0, 0, 1, 1})
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	err = SaveFile(path, index)
+	if err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+	loaded, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	if loaded.NumVectors() != 2 {
+		t.Fatalf("expected 2 vectors, got %d", loaded.NumVectors())
+	}
+
+	binaryPath := filepath.Join(t.TempDir(), "binary")
+	binaryIndex, _ := NewBinaryIndex(16, AsBinaryFlat())
+	err = binaryIndex.Add([]uint8{1, 2, 3, 4})
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	err = SaveBinaryFile(binaryPath, binaryIndex)
+	if err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+	loadedBinary, err := LoadBinaryFile(binaryPath)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	if loadedBinary.NumVectors() != 2 {
+		t.Fatalf("expected 2 vectors, got %d", loadedBinary.NumVectors())
+	}
+}
+
+func TestWriteFileAtomicKeepsPreviousFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index")
+	err := os.WriteFile(path, []byte("previous"), 0o644)
+	if err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	errWrite := errors.New("write failed")
+	err = writeFileAtomic(path, func(w io.Writer) error {
+		w.Write([]byte("partial"))
+		return errWrite
+	})
+	if !errors.Is(err, errWrite) {
+		t.Fatalf("expected %v, got %v", errWrite, err)
+	}
+
+	content, _ := os.ReadFile(path)
+	if string(content) != "previous" {
+		t.Fatalf("expected previous content, got %q", content)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("expected temporary file to be removed, got %d entries", len(entries))
+	}
+}
+
+func TestSaveFileWithGzip(t *testing.T) {
+	numFeatures := 4
+	data := make([]float32, 256*numFeatures)
+	for i := range data {
+		data[i] = float32(i % 7)
+	}
+	index, _ := NewIndex(numFeatures, AsIVFFlat(4, WithIVFMaxIterations(5), WithIVFSeed(1)))
+	err := index.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+	err = index.Add(data)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	dir := t.TempDir()
+	plainPath := filepath.Join(dir, "plain")
+	gzipPath := filepath.Join(dir, "gzip")
+	err = SaveFile(plainPath, index)
+	if err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+	err = SaveFile(gzipPath, index, WithGzip(gzip.BestCompression))
+	if err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+
+	plain, _ := os.ReadFile(plainPath)
+	compressed, _ := os.ReadFile(gzipPath)
+	if !bytes.HasPrefix(compressed, gzipMagic) {
+		t.Fatalf("expected a gzip stream")
+	}
+	if len(compressed) >= len(plain) {
+		t.Fatalf("expected compressed size %d to be less than %d", len(compressed), len(plain))
+	}
+
+	loaded, err := LoadFile(gzipPath)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	results, distances, _ := index.Search(data[:8*numFeatures], 3)
+	loadedResults, loadedDistances, err := loaded.Search(data[:8*numFeatures], 3)
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if !reflect.DeepEqual(results, loadedResults) || !reflect.DeepEqual(distances, loadedDistances) {
+		t.Fatalf("search results differ after loading a compressed index")
+	}
+
+	binaryPath := filepath.Join(dir, "binary")
+	binaryIndex, _ := NewBinaryIndex(16, AsBinaryFlat())
+	binaryIndex.Add([]uint8{1, 2, 3, 4})
+	err = SaveBinaryFile(binaryPath, binaryIndex, WithGzip(gzip.DefaultCompression))
+	if err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+	loadedBinary, err := LoadBinaryFile(binaryPath)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	if loadedBinary.NumVectors() != 2 {
+		t.Fatalf("expected 2 vectors, got %d", loadedBinary.NumVectors())
+	}
+
+	err = SaveFile(plainPath, index, WithGzip(10))
+	if !errors.Is(err, ErrInvalidCompressionLevel) {
+		t.Fatalf("expected %v, got %v", ErrInvalidCompressionLevel, err)
+	}
+}
+
+func TestDeltaEncodeMapping(t *testing.T) {
+	mapping := [][]int{{0, 3, 4, 10}, {}, {7, 2, 9}}
+	deltas := deltaEncodeMapping(mapping)
+	if !reflect.DeepEqual(deltas, [][]int{{0, 3, 1, 6}, {}, {7, -5, 7}}) {
+		t.Fatalf("unexpected deltas %v", deltas)
+	}
+	deltaDecodeMapping(deltas)
+	if !reflect.DeepEqual(deltas, mapping) {
+		t.Fatalf("expected %v, got %v", mapping, deltas)
+	}
+}
@@ -40,11 +40,11 @@ func (index *FlatIndex) Train(data []float32) error {
 
 func (index *FlatIndex) Add(data []float32) error {
 	if len(data) == 0 {
-		return ErrEmptyData
+		return newIndexError(OpAdd, IndexTypeFlat, ErrEmptyData)
 	}
 
 	if len(data)%index.state.NumFeatures != 0 {
-		return ErrInvalidDataLength
+		return newIndexError(OpAdd, IndexTypeFlat, ErrInvalidDataLength, "dataLength", len(data), "numFeatures", index.state.NumFeatures)
 	}
 
 	index.state.Data = append(index.state.Data, data...)
@@ -53,15 +53,15 @@ func (index *FlatIndex) Add(data []float32) error {
 
 func (index *FlatIndex) Search(query []float32, k int) ([][]int, [][]float32, error) {
 	if k <= 0 {
-		return nil, nil, ErrInvalidK
+		return nil, nil, newIndexError(OpSearch, IndexTypeFlat, ErrInvalidK, "k", k)
 	}
 
 	if len(query) == 0 {
-		return nil, nil, ErrEmptyData
+		return nil, nil, newIndexError(OpSearch, IndexTypeFlat, ErrEmptyData)
 	}
 
 	if len(query)%index.state.NumFeatures != 0 {
-		return nil, nil, ErrInvalidDataLength
+		return nil, nil, newIndexError(OpSearch, IndexTypeFlat, ErrInvalidDataLength, "dataLength", len(query), "numFeatures", index.state.NumFeatures)
 	}
 
 	N := len(index.state.Data) / index.state.NumFeatures
@@ -81,11 +81,12 @@ func (index *FlatIndex) Search(query []float32, k int) ([][]int, [][]float32, er
 	results := make([][]int, numQueries)
 	distances := make([][]float32, numQueries)
 	for q := range numQueries {
-		results[q] = make([]int, k)
-		distances[q] = make([]float32, k)
-		for i := range k {
-			results[q][i] = neighbors[q].SmallestK()[i].index
-			distances[q][i] = neighbors[q].SmallestK()[i].value
+		smallestK := neighbors[q].SmallestK()
+		results[q] = make([]int, len(smallestK))
+		distances[q] = make([]float32, len(smallestK))
+		for i, item := range smallestK {
+			results[q][i] = item.index
+			distances[q][i] = item.value
 		}
 	}
 
@@ -96,6 +97,56 @@ func (index *FlatIndex) NumVectors() int {
 	return len(index.state.Data) / index.state.NumFeatures
 }
 
+func (index *FlatIndex) NumFeatures() int {
+	return index.state.NumFeatures
+}
+
+func (index *FlatIndex) Describe() string {
+	return "Flat"
+}
+
+func (index *FlatIndex) Info() IndexInfo {
+	return IndexInfo{
+		IndexType:   IndexTypeFlat,
+		CodeType1:   CodeTypeNameNone,
+		CodeType2:   CodeTypeNameNone,
+		Spec:        index.Describe(),
+		NumFeatures: index.state.NumFeatures,
+		NumVectors:  index.NumVectors(),
+		IsTrained:   true,
+		Memory: MemoryUsage{
+			Data: sizeOf[float32](cap(index.state.Data)),
+		}.withTotal(),
+	}
+}
+
+// emptyCopy returns a new index without vectors.
+func (index *FlatIndex) emptyCopy() (ANNIndex, error) {
+	return newFlatIndex(index.state.NumFeatures)
+}
+
+// Merge appends the vectors of other, which keep their order and are
+// numbered after the vectors of index.
+func (index *FlatIndex) Merge(other ANNIndex) error {
+	err := index.checkMerge(other)
+	if err != nil {
+		return err
+	}
+	index.state.Data = append(index.state.Data, other.(*FlatIndex).state.Data...)
+	return nil
+}
+
+func (index *FlatIndex) checkMerge(other ANNIndex) error {
+	o, ok := other.(*FlatIndex)
+	if !ok {
+		return newIndexError(OpMerge, IndexTypeFlat, ErrIncompatibleIndex)
+	}
+	if o.state.NumFeatures != index.state.NumFeatures {
+		return newIndexError(OpMerge, IndexTypeFlat, ErrIncompatibleIndex, "numFeatures", index.state.NumFeatures, "otherNumFeatures", o.state.NumFeatures)
+	}
+	return nil
+}
+
 func (index *FlatIndex) Save(enc *gob.Encoder) error {
 	meta := MetaData{
 		IndexType: IndexTypeFlat,
@@ -3,6 +3,7 @@ package vanadium_index
 import (
 	"bytes"
 	"encoding/gob"
+	"errors"
 	"testing"
 )
 
@@ -68,3 +69,14 @@ func TestFlatIndexSaveLoad(t *testing.T) {
 		}
 	}
 }
+
+func TestFlatIndexMerge(t *testing.T) {
+	model, _ := newFlatIndex(2)
+	testMerge(t, model, []float32{1, 2, 3, 4, 5, 6, 7, 8}, 2)
+
+	other, _ := newFlatIndex(3)
+	err := model.Merge(other)
+	if !errors.Is(err, ErrIncompatibleIndex) {
+		t.Fatalf("expected %v, got %v", ErrIncompatibleIndex, err)
+	}
+}
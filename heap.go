@@ -31,6 +31,27 @@ func (h *MaxHeap) Pop() any {
 	return item
 }
 
+type MinHeap []heapItem
+
+func (h MinHeap) Len() int { return len(h) }
+
+func (h MinHeap) Less(i, j int) bool {
+	return h[i].value < h[j].value
+}
+func (h MinHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *MinHeap) Push(x any) {
+	*h = append(*h, x.(heapItem))
+}
+
+func (h *MinHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[0 : n-1]
+	return item
+}
+
 type SmallestK struct {
 	maxHeap *MaxHeap
 	k       int
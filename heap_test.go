@@ -1,6 +1,7 @@
 package vanadium_index
 
 import (
+	"container/heap"
 	"testing"
 )
 
@@ -48,3 +49,19 @@ func TestSmallestKOverK(t *testing.T) {
 		t.Fatalf("smallestK[2].index is not 2")
 	}
 }
+
+func TestMinHeap(t *testing.T) {
+	h := &MinHeap{}
+	heap.Init(h)
+	values := []float32{0.3, 0.1, 0.2}
+	for i, value := range values {
+		heap.Push(h, heapItem{index: i, value: value})
+	}
+	expected := []int{1, 2, 0}
+	for _, index := range expected {
+		item := heap.Pop(h).(heapItem)
+		if item.index != index {
+			t.Fatalf("item.index is not %d", index)
+		}
+	}
+}
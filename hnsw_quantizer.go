@@ -0,0 +1,198 @@
+package vanadium_index
+
+import (
+	"container/heap"
+	"math"
+	"math/rand/v2"
+	"sort"
+)
+
+// hnswQuantizer is a hierarchical navigable small world graph over the coarse
+// centroids of an InvertedFileIndex. It finds the nearest centroid in
+// sub-linear time, which matters once the number of lists is in the tens of
+// thousands.
+type hnswQuantizer struct {
+	state   *HNSWQuantizerState
+	vectors [][]float32
+}
+
+type HNSWQuantizerState struct {
+	M              int
+	EfConstruction int
+	EfSearch       int
+	EntryPoint     int
+	MaxLevel       int
+	Levels         []int
+	Neighbors      [][][]int
+}
+
+func newHNSWQuantizer(vectors [][]float32, m, efConstruction, efSearch int, rng *rand.Rand) *hnswQuantizer {
+	q := &hnswQuantizer{
+		state: &HNSWQuantizerState{
+			M:              m,
+			EfConstruction: efConstruction,
+			EfSearch:       efSearch,
+			EntryPoint:     0,
+			MaxLevel:       -1,
+			Levels:         make([]int, len(vectors)),
+			Neighbors:      make([][][]int, len(vectors)),
+		},
+		vectors: vectors,
+	}
+
+	levelMultiplier := 1 / math.Log(float64(max(m, 2)))
+	for n := range vectors {
+		level := int(math.Floor(-math.Log(1-rng.Float64()) * levelMultiplier))
+		q.insert(n, level)
+	}
+	return q
+}
+
+func loadHNSWQuantizer(state *HNSWQuantizerState, vectors [][]float32) *hnswQuantizer {
+	return &hnswQuantizer{
+		state:   state,
+		vectors: vectors,
+	}
+}
+
+func (q *hnswQuantizer) Predict(data []float32, numFeatures int, fn func(row int, minCol int, minVal float32) error) error {
+	if len(data) == 0 {
+		return ErrEmptyData
+	}
+	if len(data)%numFeatures != 0 {
+		return ErrInvalidDataLength
+	}
+
+	for n := range len(data) / numFeatures {
+		x := data[n*numFeatures : (n+1)*numFeatures]
+		nearest := q.search(x, 1)
+		err := fn(n, nearest[0].index, nearest[0].value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q *hnswQuantizer) search(x []float32, k int) []heapItem {
+	entry := q.state.EntryPoint
+	entryDist := q.squaredEuclideanDistance(x, q.vectors[entry])
+	for level := q.state.MaxLevel; level > 0; level-- {
+		entry, entryDist = q.greedySearch(x, entry, entryDist, level)
+	}
+
+	candidates := q.searchLayer(x, entry, entryDist, max(q.state.EfSearch, k), 0)
+	return candidates[:min(k, len(candidates))]
+}
+
+func (q *hnswQuantizer) insert(node, level int) {
+	q.state.Levels[node] = level
+	q.state.Neighbors[node] = make([][]int, level+1)
+
+	if q.state.MaxLevel < 0 {
+		q.state.EntryPoint = node
+		q.state.MaxLevel = level
+		return
+	}
+
+	x := q.vectors[node]
+	entry := q.state.EntryPoint
+	entryDist := q.squaredEuclideanDistance(x, q.vectors[entry])
+	for l := q.state.MaxLevel; l > level; l-- {
+		entry, entryDist = q.greedySearch(x, entry, entryDist, l)
+	}
+
+	for l := min(level, q.state.MaxLevel); l >= 0; l-- {
+		candidates := q.searchLayer(x, entry, entryDist, q.state.EfConstruction, l)
+		maxNeighbors := q.maxNeighbors(l)
+		for _, candidate := range candidates[:min(maxNeighbors, len(candidates))] {
+			q.state.Neighbors[node][l] = append(q.state.Neighbors[node][l], candidate.index)
+			q.connect(candidate.index, node, l)
+		}
+		entry, entryDist = candidates[0].index, candidates[0].value
+	}
+
+	if level > q.state.MaxLevel {
+		q.state.EntryPoint = node
+		q.state.MaxLevel = level
+	}
+}
+
+func (q *hnswQuantizer) connect(from, to, level int) {
+	neighbors := append(q.state.Neighbors[from][level], to)
+	maxNeighbors := q.maxNeighbors(level)
+	if len(neighbors) > maxNeighbors {
+		x := q.vectors[from]
+		sort.Slice(neighbors, func(i, j int) bool {
+			return q.squaredEuclideanDistance(x, q.vectors[neighbors[i]]) < q.squaredEuclideanDistance(x, q.vectors[neighbors[j]])
+		})
+		neighbors = neighbors[:maxNeighbors]
+	}
+	q.state.Neighbors[from][level] = neighbors
+}
+
+func (q *hnswQuantizer) greedySearch(x []float32, entry int, entryDist float32, level int) (int, float32) {
+	for changed := true; changed; {
+		changed = false
+		for _, neighbor := range q.state.Neighbors[entry][level] {
+			dist := q.squaredEuclideanDistance(x, q.vectors[neighbor])
+			if dist < entryDist {
+				entry, entryDist = neighbor, dist
+				changed = true
+			}
+		}
+	}
+	return entry, entryDist
+}
+
+// searchLayer returns up to ef nearest nodes on the given level, sorted by
+// ascending distance.
+func (q *hnswQuantizer) searchLayer(x []float32, entry int, entryDist float32, ef int, level int) []heapItem {
+	visited := map[int]struct{}{entry: {}}
+	candidates := &MinHeap{{index: entry, value: entryDist}}
+	results := &MaxHeap{{index: entry, value: entryDist}}
+
+	for candidates.Len() > 0 {
+		nearest := heap.Pop(candidates).(heapItem)
+		if nearest.value > (*results)[0].value && results.Len() >= ef {
+			break
+		}
+		for _, neighbor := range q.state.Neighbors[nearest.index][level] {
+			if _, ok := visited[neighbor]; ok {
+				continue
+			}
+			visited[neighbor] = struct{}{}
+			dist := q.squaredEuclideanDistance(x, q.vectors[neighbor])
+			if results.Len() < ef || dist < (*results)[0].value {
+				heap.Push(candidates, heapItem{index: neighbor, value: dist})
+				heap.Push(results, heapItem{index: neighbor, value: dist})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	sorted := make([]heapItem, results.Len())
+	copy(sorted, *results)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].value < sorted[j].value
+	})
+	return sorted
+}
+
+func (q *hnswQuantizer) maxNeighbors(level int) int {
+	if level == 0 {
+		return 2 * q.state.M
+	}
+	return q.state.M
+}
+
+func (q *hnswQuantizer) squaredEuclideanDistance(x, y []float32) float32 {
+	distance := float32(0)
+	for i := range x {
+		diff := x[i] - y[i]
+		distance += diff * diff
+	}
+	return distance
+}
@@ -0,0 +1,51 @@
+package vanadium_index
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestHNSWQuantizer(t *testing.T) {
+	numFeatures := 8
+	numCentroids := 500
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	centroids := make([][]float32, numCentroids)
+	for i := range centroids {
+		centroids[i] = make([]float32, numFeatures)
+		for j := range centroids[i] {
+			centroids[i][j] = rng.Float32()
+		}
+	}
+	q := newHNSWQuantizer(centroids, 16, 64, 64, rng)
+
+	numQueries := 100
+	query := make([]float32, numQueries*numFeatures)
+	for i := range query {
+		query[i] = rng.Float32()
+	}
+
+	hits := 0
+	err := q.Predict(query, numFeatures, func(row int, minCol int, minVal float32) error {
+		x := query[row*numFeatures : (row+1)*numFeatures]
+		expected := 0
+		expectedDist := q.squaredEuclideanDistance(x, centroids[0])
+		for c := 1; c < numCentroids; c++ {
+			dist := q.squaredEuclideanDistance(x, centroids[c])
+			if dist < expectedDist {
+				expected, expectedDist = c, dist
+			}
+		}
+		if minCol == expected {
+			hits++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to predict: %v", err)
+	}
+
+	if recall := float32(hits) / float32(numQueries); recall < 0.95 {
+		t.Fatalf("recall = %f, expected >= 0.95", recall)
+	}
+}
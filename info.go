@@ -0,0 +1,95 @@
+package vanadium_index
+
+import (
+	"math/bits"
+	"reflect"
+	"unsafe"
+)
+
+// IndexInfo describes what an index is and how much memory it holds. Fields
+// that do not apply to an index type are left zero.
+type IndexInfo struct {
+	IndexType   IndexType
+	CodeType1   CodeTypeName
+	CodeType2   CodeTypeName
+	Spec        string
+	NumFeatures int
+	NumVectors  int
+	IsTrained   bool
+
+	NumSubspaces    int
+	NumClusters     int
+	MaxIterations   int
+	Tolerance       float32
+	TrainSampleSize int
+	KFactor         int
+	NumShards       int
+
+	Memory MemoryUsage
+
+	// ListSizes and ListSizeHistogram are set for inverted file indexes.
+	// ListSizeHistogram[0] counts empty lists and ListSizeHistogram[i]
+	// counts lists holding between 2^(i-1) and 2^i-1 vectors.
+	ListSizes         *ListSizeStats
+	ListSizeHistogram []int
+
+	// Sub describes the index wrapped by a refine index, or the index used
+	// within each list of an inverted file index or each shard of a sharded
+	// index with the counts and memory of all lists or shards combined.
+	Sub *IndexInfo
+}
+
+// MemoryUsage reports the size in bytes of the buffers held by an index.
+type MemoryUsage struct {
+	Data      int
+	Codes     int
+	Codebooks int
+	Mapping   int
+	Graph     int
+	Total     int
+}
+
+func (m MemoryUsage) add(other MemoryUsage) MemoryUsage {
+	m.Data += other.Data
+	m.Codes += other.Codes
+	m.Codebooks += other.Codebooks
+	m.Mapping += other.Mapping
+	m.Graph += other.Graph
+	m.Total += other.Total
+	return m
+}
+
+func (m MemoryUsage) withTotal() MemoryUsage {
+	m.Total = m.Data + m.Codes + m.Codebooks + m.Mapping + m.Graph
+	return m
+}
+
+func codeTypeName[T CodeType]() CodeTypeName {
+	var t T
+	return CodeTypeName(reflect.TypeOf(t).String())
+}
+
+func sizeOf[T any](n int) int {
+	var t T
+	return n * int(unsafe.Sizeof(t))
+}
+
+func mappingSize(mapping [][]int) int {
+	size := 0
+	for _, ids := range mapping {
+		size += sizeOf[int](cap(ids))
+	}
+	return size
+}
+
+func listSizeHistogram(sizes []int) []int {
+	histogram := []int{}
+	for _, size := range sizes {
+		bucket := bits.Len(uint(size))
+		for len(histogram) <= bucket {
+			histogram = append(histogram, 0)
+		}
+		histogram[bucket]++
+	}
+	return histogram
+}
@@ -0,0 +1,99 @@
+package vanadium_index
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIndexInfo(t *testing.T) {
+	numFeatures := 4
+	data := []float32{
+		0.1, 0.2, 0.3, 0.4,
+		0.5, 0.6, 0.7, 0.8,
+		0.9, 1.0, 1.1, 1.2,
+		1.3, 1.4, 1.5, 1.6,
+		1.7, 1.8, 1.9, 2.0,
+		2.1, 2.2, 2.3, 2.4,
+	}
+
+	index, err := NewIndex(numFeatures, AsRefine(AsIVFPQ(2, 2, 2, WithIVFMaxIterations(10), WithIVFSeed(1)), 2))
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	err = index.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+	err = index.Add(data)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	info := index.Info()
+	if info.IndexType != IndexTypeRefine || info.Spec != "IVF2,PQ2x1,Refine2" || info.KFactor != 2 {
+		t.Fatalf("unexpected refine info: %+v", info)
+	}
+	if info.NumVectors != 6 || !info.IsTrained {
+		t.Fatalf("unexpected refine info: %+v", info)
+	}
+
+	ivf := info.Sub
+	if ivf.IndexType != IndexTypeIVF || ivf.CodeType1 != CodeTypeNameUint8 || ivf.CodeType2 != CodeTypeNameUint8 {
+		t.Fatalf("unexpected ivf info: %+v", ivf)
+	}
+	if ivf.NumClusters != 2 || ivf.MaxIterations != 10 || ivf.NumVectors != 6 {
+		t.Fatalf("unexpected ivf info: %+v", ivf)
+	}
+	if ivf.ListSizes.TotalCount != 6 || ivf.ListSizes.NumLists != 2 {
+		t.Fatalf("unexpected list sizes: %+v", ivf.ListSizes)
+	}
+	numLists := 0
+	for _, count := range ivf.ListSizeHistogram {
+		numLists += count
+	}
+	if numLists != 2 {
+		t.Fatalf("expected histogram over 2 lists, got %v", ivf.ListSizeHistogram)
+	}
+
+	pq := ivf.Sub
+	if pq.IndexType != IndexTypePQ || pq.NumSubspaces != 2 || pq.NumClusters != 2 || pq.NumVectors != 6 {
+		t.Fatalf("unexpected pq info: %+v", pq)
+	}
+	if pq.Memory.Codes < 6*2 || pq.Memory.Codebooks == 0 {
+		t.Fatalf("unexpected pq memory: %+v", pq.Memory)
+	}
+
+	if info.Memory.Data < 6*numFeatures*4 {
+		t.Fatalf("expected refine data of at least %d bytes, got %d", 6*numFeatures*4, info.Memory.Data)
+	}
+	total := info.Memory.Data + info.Memory.Codes + info.Memory.Codebooks + info.Memory.Mapping + info.Memory.Graph
+	if info.Memory.Total != total {
+		t.Fatalf("expected total memory %d, got %d", total, info.Memory.Total)
+	}
+	if info.Memory.Total <= ivf.Memory.Total || ivf.Memory.Total <= pq.Memory.Total {
+		t.Fatalf("expected memory to include sub indexes: %+v", info.Memory)
+	}
+}
+
+func TestBinaryIndexInfo(t *testing.T) {
+	index, err := NewBinaryIndex(16, AsBinaryFlat())
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	err = index.Add([]uint8{1, 2, 3, 4})
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	info := index.Info()
+	if info.IndexType != IndexTypeBinaryFlat || info.NumFeatures != 16 || info.NumVectors != 2 {
+		t.Fatalf("unexpected binary info: %+v", info)
+	}
+}
+
+func TestListSizeHistogram(t *testing.T) {
+	histogram := listSizeHistogram([]int{0, 1, 2, 3, 4, 0})
+	expected := []int{2, 1, 2, 1}
+	if !reflect.DeepEqual(histogram, expected) {
+		t.Fatalf("expected %v, got %v", expected, histogram)
+	}
+}
@@ -7,7 +7,32 @@ type ANNIndex interface {
 	Add(data []float32) error
 	Search(query []float32, k int) ([][]int, [][]float32, error)
 	NumVectors() int
+	NumFeatures() int
 	Save(enc *gob.Encoder) error
+	// Describe returns the index spec accepted by NewIndexFromSpec.
+	Describe() string
+	Info() IndexInfo
+	// Merge appends the vectors of other, which must have been trained with
+	// the same model.
+	Merge(other ANNIndex) error
+
+	encode(enc *gob.Encoder) error
+	decode(dec *gob.Decoder) error
+	checkMerge(other ANNIndex) error
+	// emptyCopy returns a new index holding a copy of the trained model of
+	// the index and none of its vectors.
+	emptyCopy() (ANNIndex, error)
+}
+
+type BinaryANNIndex interface {
+	Train(data []uint8) error
+	Add(data []uint8) error
+	Search(query []uint8, k int) ([][]int, [][]int, error)
+	NumVectors() int
+	Save(enc *gob.Encoder) error
+	// Describe returns the index spec accepted by NewBinaryIndexFromSpec.
+	Describe() string
+	Info() IndexInfo
 
 	encode(enc *gob.Encoder) error
 	decode(dec *gob.Decoder) error
@@ -17,6 +42,10 @@ type CodeType interface {
 	~uint8 | ~uint16 | ~uint32
 }
 
+type trainingReporter interface {
+	trainingReport() *TrainingReport
+}
+
 type subIndexBuilder interface {
-	build(numFeatures int) (ANNIndex, error)
+	build(numFeatures int, list int) (ANNIndex, error)
 }
@@ -1,6 +1,10 @@
 package vanadium_index
 
 import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"slices"
 	"testing"
 )
 
@@ -37,3 +41,99 @@ func TestInvertedFilePQIndexInterface(t *testing.T) {
 		t.Fatalf("index is not a InvertedFileIndex")
 	}
 }
+
+func TestRefineIndexInterface(t *testing.T) {
+	var index ANNIndex
+	base, _ := newFlatIndex(2)
+	index, _ = newRefineIndex(2, base, 2, WithRefineScalarQuantizer())
+	if _, ok := index.(*RefineIndex); !ok {
+		t.Fatalf("index is not a RefineIndex")
+	}
+}
+
+func TestBinaryFlatIndexInterface(t *testing.T) {
+	var index BinaryANNIndex
+	index, _ = newBinaryFlatIndex(16)
+	if _, ok := index.(*BinaryFlatIndex); !ok {
+		t.Fatalf("index is not a BinaryFlatIndex")
+	}
+}
+
+func TestBinaryInvertedFileIndexInterface(t *testing.T) {
+	var index BinaryANNIndex
+	index, _ = newBinaryInvertedFileIndex[uint8](16, 2, WithBinaryIVFMaxIterations(10))
+	if _, ok := index.(*BinaryInvertedFileIndex[uint8]); !ok {
+		t.Fatalf("index is not a BinaryInvertedFileIndex")
+	}
+}
+
+// cloneIndex returns a copy of index through a save and load round trip.
+func cloneIndex(t *testing.T, index ANNIndex) ANNIndex {
+	t.Helper()
+	var buf bytes.Buffer
+	err := index.Save(gob.NewEncoder(&buf))
+	if err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+	cloned, err := LoadIndex(gob.NewDecoder(&buf))
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	return cloned
+}
+
+// testMerge adds the two halves of data to clones of model, merges them and
+// checks that searching the merged index matches adding data to one clone.
+func testMerge(t *testing.T, model ANNIndex, data []float32, numFeatures int) {
+	t.Helper()
+	half := len(data) / numFeatures / 2 * numFeatures
+
+	expected := cloneIndex(t, model)
+	merged := cloneIndex(t, model)
+	shard := cloneIndex(t, model)
+	for _, add := range []struct {
+		index ANNIndex
+		data  []float32
+	}{
+		{expected, data},
+		{merged, data[:half]},
+		{shard, data[half:]},
+	} {
+		err := add.index.Add(add.data)
+		if err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+	}
+
+	err := merged.Merge(shard)
+	if err != nil {
+		t.Fatalf("Failed to merge index: %v", err)
+	}
+	if merged.NumVectors() != expected.NumVectors() {
+		t.Fatalf("expected %d vectors, got %d", expected.NumVectors(), merged.NumVectors())
+	}
+
+	k := len(data) / numFeatures
+	expectedResults, expectedDistances, err := expected.Search(data, k)
+	if err != nil {
+		t.Fatalf("Failed to search index: %v", err)
+	}
+	results, distances, err := merged.Search(data, k)
+	if err != nil {
+		t.Fatalf("Failed to search index: %v", err)
+	}
+	for q := range results {
+		if !reflect.DeepEqual(sortedInts(results[q]), sortedInts(expectedResults[q])) {
+			t.Fatalf("query %d: expected results %v, got %v", q, expectedResults[q], results[q])
+		}
+		if !reflect.DeepEqual(distances[q], expectedDistances[q]) {
+			t.Fatalf("query %d: expected distances %v, got %v", q, expectedDistances[q], distances[q])
+		}
+	}
+}
+
+func sortedInts(x []int) []int {
+	sorted := slices.Clone(x)
+	slices.Sort(sorted)
+	return sorted
+}
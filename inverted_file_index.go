@@ -2,6 +2,7 @@ package vanadium_index
 
 import (
 	"encoding/gob"
+	"math"
 	"reflect"
 	"runtime"
 
@@ -10,9 +11,11 @@ import (
 )
 
 type InvertedFileIndex[T1, T2 CodeType] struct {
-	state   *InvertedFileIndexState[T1, T2]
-	cluster *kmeans.KMeans
-	indexes []ANNIndex
+	state     *InvertedFileIndexState[T1, T2]
+	cluster   *kmeans.KMeans
+	quantizer *hnswQuantizer
+	indexes   []ANNIndex
+	report    *TrainingReport
 }
 
 type InvertedFileIndexState[T1, T2 CodeType] struct {
@@ -22,11 +25,37 @@ type InvertedFileIndexState[T1, T2 CodeType] struct {
 	ShouldTrainIndexes bool
 	Config             *InvertedFileIndexConfig
 	Mapping            [][]int
+	// IsMappingDelta reports that Mapping was saved delta-encoded.
+	IsMappingDelta bool
+	Quantizer      *HNSWQuantizerState
 }
 
 type InvertedFileIndexConfig struct {
-	MaxIterations int
-	Tolerance     float32
+	MaxIterations       int
+	Tolerance           float32
+	HNSWM               int
+	HNSWEfConstruction  int
+	HNSWEfSearch        int
+	MaxListSizeRatio    float32
+	MinListSizeRatio    float32
+	TrainSampleSize     int
+	Seed                uint64
+	IsSeeded            bool
+	InitMethod          InitMethod
+	MiniBatchSize       int
+	MiniBatchIterations int
+	TrainingCallback    TrainingCallback
+}
+
+type ListSizeStats struct {
+	NumLists   int
+	NumEmpty   int
+	Min        int
+	Max        int
+	Mean       float32
+	StdDev     float32
+	Imbalance  float32
+	TotalCount int
 }
 
 func newInvertedFileFlatIndex[T CodeType](
@@ -34,6 +63,10 @@ func newInvertedFileFlatIndex[T CodeType](
 	numClusters T,
 	opts ...InvertedFileIndexOption,
 ) (*InvertedFileIndex[T, T], error) {
+	if numFeatures <= 0 {
+		return nil, ErrInvalidNumFeatures
+	}
+
 	index := &InvertedFileIndex[T, T]{
 		state: &InvertedFileIndexState[T, T]{
 			NumFeatures:        numFeatures,
@@ -65,6 +98,13 @@ func newInvertedFilePQIndex[T1, T2 CodeType](
 	numPqClusters T2,
 	opts ...InvertedFileIndexOption,
 ) (*InvertedFileIndex[T1, T2], error) {
+	if numFeatures <= 0 {
+		return nil, ErrInvalidNumFeatures
+	}
+	if numPqSubspaces <= 0 || numPqSubspaces > numFeatures || numFeatures%numPqSubspaces != 0 {
+		return nil, newIndexError(OpNew, IndexTypeIVF, ErrInvalidNumSubspaces, "numSubspaces", numPqSubspaces, "numFeatures", numFeatures)
+	}
+
 	index := &InvertedFileIndex[T1, T2]{
 		state: &InvertedFileIndexState[T1, T2]{
 			NumFeatures:        numFeatures,
@@ -81,7 +121,7 @@ func newInvertedFilePQIndex[T1, T2 CodeType](
 
 	pqOpts := []ProductQuantizationIndexOption{}
 	for _, opt := range opts {
-		err := opt(index.state.Config, pqOpts)
+		err := opt(index.state.Config, &pqOpts)
 		if err != nil {
 			return nil, err
 		}
@@ -90,6 +130,8 @@ func newInvertedFilePQIndex[T1, T2 CodeType](
 		numSubspaces: numPqSubspaces,
 		numClusters:  numPqClusters,
 		opts:         pqOpts,
+		seed:         index.state.Config.Seed,
+		isSeeded:     index.state.Config.IsSeeded,
 	}
 	return newInvertedFileIndex(index, indexBuilder)
 }
@@ -110,7 +152,7 @@ func newInvertedFileIndex[T1, T2 CodeType](
 	cluster, err := kmeans.NewKMeans(
 		int(index.state.NumClusters),
 		index.state.NumFeatures,
-		kmeans.WithInitMethod(kmeans.INIT_KMEANS_PLUS_PLUS),
+		kmeans.WithInitMethod(index.state.Config.InitMethod.kmeansInitMethod()),
 	)
 	if err != nil {
 		return nil, err
@@ -119,52 +161,108 @@ func newInvertedFileIndex[T1, T2 CodeType](
 
 	index.indexes = make([]ANNIndex, index.state.NumClusters)
 	for c := range int(index.state.NumClusters) {
-		subIndex, err := indexBuilder.build(index.state.NumFeatures)
+		subIndex, err := indexBuilder.build(index.state.NumFeatures, c)
 		if err != nil {
 			return nil, err
 		}
 		index.indexes[c] = subIndex
 	}
 	index.state.Mapping = make([][]int, index.state.NumClusters)
+
+	if sampleSize := index.state.Config.TrainSampleSize; sampleSize > 0 && sampleSize < index.minTrainingVectors() {
+		return nil, newIndexError(
+			OpNew, IndexTypeIVF, ErrInvalidSampleSize,
+			"required", index.minTrainingVectors(), "actual", sampleSize,
+		)
+	}
 	return index, nil
 }
 
 func (index *InvertedFileIndex[T1, T2]) Train(data []float32) error {
 	if len(data) == 0 {
-		return ErrEmptyData
+		return newIndexError(OpTrain, IndexTypeIVF, ErrEmptyData)
 	}
 
 	if len(data)%index.state.NumFeatures != 0 {
-		return ErrInvalidDataLength
+		return newIndexError(OpTrain, IndexTypeIVF, ErrInvalidDataLength, "dataLength", len(data), "numFeatures", index.state.NumFeatures)
 	}
 
-	_, _, err := index.cluster.Train(
-		data,
-		kmeans.WithMaxIterations(index.state.Config.MaxIterations),
-		kmeans.WithTolerance(index.state.Config.Tolerance),
-	)
+	minTrainingVectors := index.minTrainingVectors()
+	if numVectors := len(data) / index.state.NumFeatures; numVectors < minTrainingVectors {
+		return newIndexError(
+			OpTrain, IndexTypeIVF, ErrInsufficientTrainingData,
+			"required", minTrainingVectors, "actual", numVectors,
+		)
+	}
+
+	rng := newRand(index.state.Config.Seed, index.state.Config.IsSeeded)
+	data = sampleVectors(data, index.state.NumFeatures, index.state.Config.TrainSampleSize, rng)
+
+	config := clusterTrainConfig{
+		maxIterations:       index.state.Config.MaxIterations,
+		tolerance:           index.state.Config.Tolerance,
+		miniBatchSize:       index.state.Config.MiniBatchSize,
+		miniBatchIterations: index.state.Config.MiniBatchIterations,
+	}
+	if index.state.Config.IsSeeded {
+		config.rng = rng
+	}
+
+	numIter, inertia, converged, err := trainCluster(index.cluster, data, config)
 	if err != nil {
 		return err
 	}
 
+	if index.state.Config.MaxListSizeRatio > 0 {
+		err = index.balance(data, config)
+		if err != nil {
+			return err
+		}
+	}
+
+	if index.state.Config.HNSWM > 0 {
+		index.quantizer = newHNSWQuantizer(
+			index.cluster.Centroids(),
+			index.state.Config.HNSWM,
+			index.state.Config.HNSWEfConstruction,
+			index.state.Config.HNSWEfSearch,
+			rng,
+		)
+		index.state.Quantizer = index.quantizer.state
+	}
+
 	numVectors := len(data) / index.state.NumFeatures
 
 	code := make([]T1, numVectors)
 	numElements := make([]int, int(index.state.NumClusters))
-	err = index.cluster.Predict(data, func(row int, minCol int, minVal float32) error {
+	sumDist := float32(0)
+	err = index.predict(data, func(row int, minCol int, minVal float32) error {
 		code[row] = T1(minCol)
 		numElements[minCol] += 1
+		sumDist += minVal
 		return nil
 	})
 	if err != nil {
 		return err
 	}
 
+	coarse := newClusteringReport(numIter, inertia, converged, numElements)
+	report := &TrainingReport{
+		NumVectors: numVectors,
+		Coarse:     &coarse,
+		MSE:        sumDist / float32(numVectors),
+	}
+
 	if !index.state.ShouldTrainIndexes {
-		index.state.IsTrained = true
+		index.finishTraining(report)
 		return nil
 	}
 
+	// Lists with fewer vectors than PQ clusters cannot train their own
+	// codebooks. They share codebooks trained on all training data instead.
+	minListSize := index.minListSize()
+	fallbackData := make([][]float32, index.state.NumClusters)
+
 	var eg errgroup.Group
 	eg.SetLimit(runtime.NumCPU())
 
@@ -182,64 +280,175 @@ func (index *InvertedFileIndex[T1, T2]) Train(data []float32) error {
 				countClusterData += 1
 			}
 
-			return index.indexes[c].Train(clusterData)
+			if numElements[c] < minListSize {
+				fallbackData[c] = clusterData
+				return nil
+			}
+			err := index.indexes[c].Train(clusterData)
+			if err != nil {
+				return newIndexError(OpTrain, IndexTypeIVF, err).withList(c)
+			}
+			return nil
 		})
 	}
 	if err := eg.Wait(); err != nil {
 		return err
 	}
-	index.state.IsTrained = true
 
+	for c := range int(index.state.NumClusters) {
+		if numElements[c] < minListSize {
+			report.FallbackLists = append(report.FallbackLists, c)
+		}
+	}
+	if len(report.FallbackLists) > 0 {
+		err = index.trainFallback(data, fallbackData, report.FallbackLists)
+		if err != nil {
+			return err
+		}
+	}
+
+	report.Lists = make([]*TrainingReport, index.state.NumClusters)
+	sumDist = 0
+	for c, subIndex := range index.indexes {
+		reporter, ok := subIndex.(trainingReporter)
+		if !ok || reporter.trainingReport() == nil {
+			continue
+		}
+		report.Lists[c] = reporter.trainingReport()
+		sumDist += report.Lists[c].MSE * float32(report.Lists[c].NumVectors)
+	}
+	report.MSE = sumDist / float32(numVectors)
+	index.finishTraining(report)
+
+	return nil
+}
+
+// minTrainingVectors returns the number of vectors needed to train both the
+// coarse quantizer and, for IVF-PQ, the codebooks shared by undersized lists.
+func (index *InvertedFileIndex[T1, T2]) minTrainingVectors() int {
+	return max(int(index.state.NumClusters), index.minListSize())
+}
+
+// minListSize returns the number of training vectors a list needs to train
+// its own PQ codebooks.
+func (index *InvertedFileIndex[T1, T2]) minListSize() int {
+	subIndex, ok := index.indexes[0].(*ProductQuantizationIndex[T2])
+	if !ok {
+		return 0
+	}
+	return int(subIndex.state.NumClusters)
+}
+
+// trainFallback trains a PQ index on all training data and shares its
+// codebooks with the given lists.
+func (index *InvertedFileIndex[T1, T2]) trainFallback(data []float32, listData [][]float32, lists []int) error {
+	template := index.indexes[lists[0]].(*ProductQuantizationIndex[T2])
+	fallback, err := template.untrainedCopy()
+	if err != nil {
+		return err
+	}
+	err = fallback.Train(data)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range lists {
+		subIndex := index.indexes[c].(*ProductQuantizationIndex[T2])
+		err = subIndex.shareModel(fallback)
+		if err != nil {
+			return err
+		}
+		subIndex.report = nil
+		numVectors := len(listData[c]) / index.state.NumFeatures
+		if numVectors == 0 {
+			continue
+		}
+		sumDist, err := subIndex.quantizationError(listData[c])
+		if err != nil {
+			return err
+		}
+		subIndex.report = &TrainingReport{
+			NumVectors: numVectors,
+			MSE:        sumDist / float32(numVectors),
+		}
+	}
 	return nil
 }
 
+func (index *InvertedFileIndex[T1, T2]) finishTraining(report *TrainingReport) {
+	index.report = report
+	if index.state.Config.TrainingCallback != nil {
+		index.state.Config.TrainingCallback(report)
+	}
+	index.state.IsTrained = true
+}
+
+func (index *InvertedFileIndex[T1, T2]) trainingReport() *TrainingReport {
+	return index.report
+}
+
 func (index *InvertedFileIndex[T1, T2]) Add(data []float32) error {
 	if len(data) == 0 {
-		return ErrEmptyData
+		return newIndexError(OpAdd, IndexTypeIVF, ErrEmptyData)
 	}
 
 	if len(data)%index.state.NumFeatures != 0 {
-		return ErrInvalidDataLength
+		return newIndexError(OpAdd, IndexTypeIVF, ErrInvalidDataLength, "dataLength", len(data), "numFeatures", index.state.NumFeatures)
 	}
 
 	if !index.state.IsTrained {
-		return ErrNotTrained
+		return newIndexError(OpAdd, IndexTypeIVF, ErrNotTrained)
 	}
 
+	// Group rows by list so that each list is extended once per call.
 	ivfRow := index.NumVectors()
-	err := index.cluster.Predict(data, func(row int, minCol int, minVal float32) error {
-		rowData := data[row*index.state.NumFeatures : (row+1)*index.state.NumFeatures]
-		index.state.Mapping[minCol] = append(index.state.Mapping[minCol], ivfRow+row)
-		return index.indexes[minCol].Add(rowData)
+	rows := make([][]int, index.state.NumClusters)
+	err := index.predict(data, func(row int, minCol int, minVal float32) error {
+		rows[minCol] = append(rows[minCol], row)
+		return nil
 	})
 	if err != nil {
 		return err
 	}
 
+	for c, listRows := range rows {
+		if len(listRows) == 0 {
+			continue
+		}
+		listData := make([]float32, 0, len(listRows)*index.state.NumFeatures)
+		for _, row := range listRows {
+			listData = append(listData, data[row*index.state.NumFeatures:(row+1)*index.state.NumFeatures]...)
+			index.state.Mapping[c] = append(index.state.Mapping[c], ivfRow+row)
+		}
+		err = index.indexes[c].Add(listData)
+		if err != nil {
+			return newIndexError(OpAdd, IndexTypeIVF, err).withList(c)
+		}
+	}
 	return nil
 }
 
 func (index *InvertedFileIndex[T1, T2]) Search(query []float32, k int) ([][]int, [][]float32, error) {
 	if k <= 0 {
-		return nil, nil, ErrInvalidK
+		return nil, nil, newIndexError(OpSearch, IndexTypeIVF, ErrInvalidK, "k", k)
 	}
 
 	if len(query) == 0 {
-		return nil, nil, ErrEmptyData
+		return nil, nil, newIndexError(OpSearch, IndexTypeIVF, ErrEmptyData)
 	}
 
 	if len(query)%index.state.NumFeatures != 0 {
-		return nil, nil, ErrInvalidDataLength
+		return nil, nil, newIndexError(OpSearch, IndexTypeIVF, ErrInvalidDataLength, "dataLength", len(query), "numFeatures", index.state.NumFeatures)
 	}
 
 	if !index.state.IsTrained {
-		return nil, nil, ErrNotTrained
+		return nil, nil, newIndexError(OpSearch, IndexTypeIVF, ErrNotTrained)
 	}
 
 	numQueries := len(query) / index.state.NumFeatures
 	results := make([][]int, numQueries)
 	distances := make([][]float32, numQueries)
-	err := index.cluster.Predict(query, func(row int, minCol int, minVal float32) error {
+	err := index.predict(query, func(row int, minCol int, minVal float32) error {
 		rowQuery := query[row*index.state.NumFeatures : (row+1)*index.state.NumFeatures]
 		result, distance, err := index.indexes[minCol].Search(rowQuery, k)
 		if err != nil {
@@ -260,6 +469,174 @@ func (index *InvertedFileIndex[T1, T2]) Search(query []float32, k int) ([][]int,
 	return results, distances, nil
 }
 
+// Encode assigns each vector of data to its list and returns the lists and
+// the PQ codes of the vectors, NumSubspaces codes per vector, as Add would
+// store them. Each list has its own codebooks, so codes are only meaningful
+// together with their list.
+func (index *InvertedFileIndex[T1, T2]) Encode(data []float32) ([]int, []T2, error) {
+	if len(data) == 0 {
+		return nil, nil, newIndexError(OpEncode, IndexTypeIVF, ErrEmptyData)
+	}
+
+	if len(data)%index.state.NumFeatures != 0 {
+		return nil, nil, newIndexError(OpEncode, IndexTypeIVF, ErrInvalidDataLength, "dataLength", len(data), "numFeatures", index.state.NumFeatures)
+	}
+
+	lists, err := index.pqLists(OpEncode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	numVectors := len(data) / index.state.NumFeatures
+	assignments := make([]int, numVectors)
+	rows := make([][]int, index.state.NumClusters)
+	err = index.predict(data, func(row int, minCol int, minVal float32) error {
+		assignments[row] = minCol
+		rows[minCol] = append(rows[minCol], row)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	numSubspaces := lists[0].state.NumSubspaces
+	codes := make([]T2, numVectors*numSubspaces)
+	for c, listRows := range rows {
+		if len(listRows) == 0 {
+			continue
+		}
+		listData := make([]float32, 0, len(listRows)*index.state.NumFeatures)
+		for _, row := range listRows {
+			listData = append(listData, data[row*index.state.NumFeatures:(row+1)*index.state.NumFeatures]...)
+		}
+		listCodes, err := lists[c].Encode(listData)
+		if err != nil {
+			return nil, nil, newIndexError(OpEncode, IndexTypeIVF, err).withList(c)
+		}
+		for i, row := range listRows {
+			copy(codes[row*numSubspaces:(row+1)*numSubspaces], listCodes[i*numSubspaces:(i+1)*numSubspaces])
+		}
+	}
+	return assignments, codes, nil
+}
+
+// Decode returns the reconstruction of each vector from its list and codes,
+// as returned by Encode.
+func (index *InvertedFileIndex[T1, T2]) Decode(lists []int, codes []T2) ([]float32, error) {
+	pqLists, err := index.checkCodes(OpDecode, lists, codes)
+	if err != nil {
+		return nil, err
+	}
+
+	numSubspaces := pqLists[0].state.NumSubspaces
+	data := make([]float32, 0, len(lists)*index.state.NumFeatures)
+	for n, c := range lists {
+		vector, err := pqLists[c].Decode(codes[n*numSubspaces : (n+1)*numSubspaces])
+		if err != nil {
+			return nil, newIndexError(OpDecode, IndexTypeIVF, err).withList(c)
+		}
+		data = append(data, vector...)
+	}
+	return data, nil
+}
+
+// SearchCodes searches lists and codes supplied by the caller, as returned by
+// Encode. Like Search, each query only ranks the vectors of its nearest list.
+// Results are positions in lists, in vectors.
+func (index *InvertedFileIndex[T1, T2]) SearchCodes(query []float32, lists []int, codes []T2, k int) ([][]int, [][]float32, error) {
+	if k <= 0 {
+		return nil, nil, newIndexError(OpSearch, IndexTypeIVF, ErrInvalidK, "k", k)
+	}
+
+	if len(query) == 0 {
+		return nil, nil, newIndexError(OpSearch, IndexTypeIVF, ErrEmptyData)
+	}
+
+	if len(query)%index.state.NumFeatures != 0 {
+		return nil, nil, newIndexError(OpSearch, IndexTypeIVF, ErrInvalidDataLength, "dataLength", len(query), "numFeatures", index.state.NumFeatures)
+	}
+
+	pqLists, err := index.checkCodes(OpSearch, lists, codes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	numSubspaces := pqLists[0].state.NumSubspaces
+	positions := make([][]int, index.state.NumClusters)
+	listCodes := make([][]T2, index.state.NumClusters)
+	for n, c := range lists {
+		positions[c] = append(positions[c], n)
+		listCodes[c] = append(listCodes[c], codes[n*numSubspaces:(n+1)*numSubspaces]...)
+	}
+
+	numQueries := len(query) / index.state.NumFeatures
+	results := make([][]int, numQueries)
+	distances := make([][]float32, numQueries)
+	err = index.predict(query, func(row int, minCol int, minVal float32) error {
+		rowQuery := query[row*index.state.NumFeatures : (row+1)*index.state.NumFeatures]
+		result, distance, err := pqLists[minCol].searchCodes(rowQuery, listCodes[minCol], len(positions[minCol]), k)
+		if err != nil {
+			return err
+		}
+		results[row] = make([]int, len(result[0]))
+		distances[row] = make([]float32, len(distance[0]))
+		for i, r := range result[0] {
+			results[row][i] = positions[minCol][r]
+			distances[row][i] = distance[0][i]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return results, distances, nil
+}
+
+// pqLists returns the PQ index of each list. IVF-Flat stores no codes.
+func (index *InvertedFileIndex[T1, T2]) pqLists(op string) ([]*ProductQuantizationIndex[T2], error) {
+	if !index.state.ShouldTrainIndexes {
+		return nil, newIndexError(op, IndexTypeIVF, ErrNoCodes)
+	}
+
+	if !index.state.IsTrained {
+		return nil, newIndexError(op, IndexTypeIVF, ErrNotTrained)
+	}
+
+	lists := make([]*ProductQuantizationIndex[T2], len(index.indexes))
+	for c, subIndex := range index.indexes {
+		lists[c] = subIndex.(*ProductQuantizationIndex[T2])
+	}
+	return lists, nil
+}
+
+// checkCodes validates lists and codes supplied by the caller.
+func (index *InvertedFileIndex[T1, T2]) checkCodes(op string, lists []int, codes []T2) ([]*ProductQuantizationIndex[T2], error) {
+	pqLists, err := index.pqLists(op)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lists) == 0 {
+		return nil, newIndexError(op, IndexTypeIVF, ErrEmptyData)
+	}
+
+	numSubspaces := pqLists[0].state.NumSubspaces
+	if len(codes) != len(lists)*numSubspaces {
+		return nil, newIndexError(op, IndexTypeIVF, ErrInvalidDataLength, "dataLength", len(codes), "numVectors", len(lists), "numSubspaces", numSubspaces)
+	}
+
+	for n, c := range lists {
+		if c < 0 || c >= int(index.state.NumClusters) {
+			return nil, newIndexError(op, IndexTypeIVF, ErrInvalidCode, "list", c, "numClusters", int(index.state.NumClusters))
+		}
+		err := pqLists[c].checkCodes(op, codes[n*numSubspaces:(n+1)*numSubspaces])
+		if err != nil {
+			return nil, newIndexError(op, IndexTypeIVF, err).withList(c)
+		}
+	}
+	return pqLists, nil
+}
+
 func (index *InvertedFileIndex[T1, T2]) NumVectors() int {
 	numVectors := 0
 	for _, index := range index.indexes {
@@ -268,6 +645,185 @@ func (index *InvertedFileIndex[T1, T2]) NumVectors() int {
 	return numVectors
 }
 
+func (index *InvertedFileIndex[T1, T2]) NumFeatures() int {
+	return index.state.NumFeatures
+}
+
+func (index *InvertedFileIndex[T1, T2]) Describe() string {
+	return describeIVF(int(index.state.NumClusters), index.state.Config) + "," + index.indexes[0].Describe()
+}
+
+func (index *InvertedFileIndex[T1, T2]) Info() IndexInfo {
+	graph := 0
+	if index.quantizer != nil {
+		graph += sizeOf[int](len(index.quantizer.state.Levels))
+		for _, levels := range index.quantizer.state.Neighbors {
+			for _, neighbors := range levels {
+				graph += sizeOf[int](cap(neighbors))
+			}
+		}
+	}
+	memory := MemoryUsage{
+		Codebooks: sizeOf[float32](int(index.state.NumClusters) * index.state.NumFeatures),
+		Mapping:   mappingSize(index.state.Mapping),
+		Graph:     graph,
+	}.withTotal()
+
+	sub := index.indexes[0].Info()
+	sub.NumVectors = 0
+	sub.Memory = MemoryUsage{}
+	for _, subIndex := range index.indexes {
+		subInfo := subIndex.Info()
+		sub.NumVectors += subInfo.NumVectors
+		sub.Memory = sub.Memory.add(subInfo.Memory)
+	}
+
+	listSizes := index.ListSizeStats()
+	return IndexInfo{
+		IndexType:         IndexTypeIVF,
+		CodeType1:         codeTypeName[T1](),
+		CodeType2:         codeTypeName[T2](),
+		Spec:              index.Describe(),
+		NumFeatures:       index.state.NumFeatures,
+		NumVectors:        index.NumVectors(),
+		IsTrained:         index.state.IsTrained,
+		NumClusters:       int(index.state.NumClusters),
+		MaxIterations:     index.state.Config.MaxIterations,
+		Tolerance:         index.state.Config.Tolerance,
+		TrainSampleSize:   index.state.Config.TrainSampleSize,
+		Memory:            memory.add(sub.Memory),
+		ListSizes:         &listSizes,
+		ListSizeHistogram: listSizeHistogram(index.ListSizes()),
+		Sub:               &sub,
+	}
+}
+
+// emptyCopy returns a new index with a copy of the coarse centroids and of
+// the model of each list, and no vectors.
+func (index *InvertedFileIndex[T1, T2]) emptyCopy() (ANNIndex, error) {
+	state := *index.state
+	config := *index.state.Config
+	state.Config = &config
+	state.Mapping = make([][]int, index.state.NumClusters)
+
+	cluster, err := copyCluster(index.cluster)
+	if err != nil {
+		return nil, err
+	}
+	copied := &InvertedFileIndex[T1, T2]{state: &state, cluster: cluster}
+	if index.quantizer != nil {
+		copied.quantizer = loadHNSWQuantizer(state.Quantizer, cluster.Centroids())
+	}
+
+	copied.indexes = make([]ANNIndex, len(index.indexes))
+	for c, subIndex := range index.indexes {
+		copied.indexes[c], err = subIndex.emptyCopy()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return copied, nil
+}
+
+// Merge appends the lists of other, which must share the coarse centroids of
+// index and, for IVF-PQ, the codebooks of each list. The ids of other are
+// shifted by the number of vectors in index.
+func (index *InvertedFileIndex[T1, T2]) Merge(other ANNIndex) error {
+	err := index.checkMerge(other)
+	if err != nil {
+		return err
+	}
+	o := other.(*InvertedFileIndex[T1, T2])
+
+	offset := index.NumVectors()
+	for c := range int(index.state.NumClusters) {
+		otherMapping := o.state.Mapping[c]
+		err = index.indexes[c].Merge(o.indexes[c])
+		if err != nil {
+			return newIndexError(OpMerge, IndexTypeIVF, err).withList(c)
+		}
+		for _, id := range otherMapping {
+			index.state.Mapping[c] = append(index.state.Mapping[c], offset+id)
+		}
+	}
+	return nil
+}
+
+func (index *InvertedFileIndex[T1, T2]) checkMerge(other ANNIndex) error {
+	o, ok := other.(*InvertedFileIndex[T1, T2])
+	if !ok {
+		return newIndexError(OpMerge, IndexTypeIVF, ErrIncompatibleIndex)
+	}
+	if o.state.NumFeatures != index.state.NumFeatures {
+		return newIndexError(OpMerge, IndexTypeIVF, ErrIncompatibleIndex, "numFeatures", index.state.NumFeatures, "otherNumFeatures", o.state.NumFeatures)
+	}
+	if o.state.NumClusters != index.state.NumClusters {
+		return newIndexError(OpMerge, IndexTypeIVF, ErrIncompatibleIndex, "numClusters", int(index.state.NumClusters), "otherNumClusters", int(o.state.NumClusters))
+	}
+	if o.NumVectors() == 0 {
+		return nil
+	}
+	if !index.state.IsTrained {
+		return newIndexError(OpMerge, IndexTypeIVF, ErrNotTrained)
+	}
+	if !equalCentroids(index.cluster.Centroids(), o.cluster.Centroids()) {
+		return newIndexError(OpMerge, IndexTypeIVF, ErrIncompatibleIndex)
+	}
+	for c := range int(index.state.NumClusters) {
+		err := index.indexes[c].checkMerge(o.indexes[c])
+		if err != nil {
+			return newIndexError(OpMerge, IndexTypeIVF, err).withList(c)
+		}
+	}
+	return nil
+}
+
+// ListSizes returns the number of vectors stored in each inverted list.
+func (index *InvertedFileIndex[T1, T2]) ListSizes() []int {
+	sizes := make([]int, len(index.state.Mapping))
+	for c, ids := range index.state.Mapping {
+		sizes[c] = len(ids)
+	}
+	return sizes
+}
+
+// ListSizeStats summarizes the distribution of ListSizes. Imbalance is the
+// ratio of the largest list to the mean list size.
+func (index *InvertedFileIndex[T1, T2]) ListSizeStats() ListSizeStats {
+	return newListSizeStats(index.ListSizes())
+}
+
+func newListSizeStats(sizes []int) ListSizeStats {
+	stats := ListSizeStats{
+		NumLists: len(sizes),
+	}
+	if len(sizes) == 0 {
+		return stats
+	}
+
+	stats.Min = sizes[0]
+	for _, size := range sizes {
+		stats.Min = min(stats.Min, size)
+		stats.Max = max(stats.Max, size)
+		stats.TotalCount += size
+		if size == 0 {
+			stats.NumEmpty++
+		}
+	}
+	stats.Mean = float32(stats.TotalCount) / float32(len(sizes))
+
+	variance := float32(0)
+	for _, size := range sizes {
+		diff := float32(size) - stats.Mean
+		variance += diff * diff
+	}
+	stats.StdDev = float32(math.Sqrt(float64(variance / float32(len(sizes)))))
+	if stats.Mean > 0 {
+		stats.Imbalance = float32(stats.Max) / stats.Mean
+	}
+	return stats
+}
+
 func (index *InvertedFileIndex[T1, T2]) Save(enc *gob.Encoder) error {
 	var t1 T1
 	var t2 T2
@@ -284,7 +840,10 @@ func (index *InvertedFileIndex[T1, T2]) Save(enc *gob.Encoder) error {
 }
 
 func (index *InvertedFileIndex[T1, T2]) encode(enc *gob.Encoder) error {
-	err := enc.Encode(index.state)
+	state := *index.state
+	state.Mapping = deltaEncodeMapping(index.state.Mapping)
+	state.IsMappingDelta = true
+	err := enc.Encode(&state)
 	if err != nil {
 		return err
 	}
@@ -309,6 +868,10 @@ func (index *InvertedFileIndex[T1, T2]) decode(dec *gob.Decoder) error {
 	if err != nil {
 		return err
 	}
+	if index.state.IsMappingDelta {
+		deltaDecodeMapping(index.state.Mapping)
+		index.state.IsMappingDelta = false
+	}
 
 	cluster, err := kmeans.LoadKMeans(dec)
 	if err != nil {
@@ -316,6 +879,10 @@ func (index *InvertedFileIndex[T1, T2]) decode(dec *gob.Decoder) error {
 	}
 	index.cluster = cluster
 
+	if index.state.Quantizer != nil {
+		index.quantizer = loadHNSWQuantizer(index.state.Quantizer, index.cluster.Centroids())
+	}
+
 	index.indexes = make([]ANNIndex, index.state.NumClusters)
 	for i := range int(index.state.NumClusters) {
 		if index.state.ShouldTrainIndexes {
@@ -331,9 +898,50 @@ func (index *InvertedFileIndex[T1, T2]) decode(dec *gob.Decoder) error {
 	return nil
 }
 
+// balance splits oversized clusters and merges tiny ones according to
+// MaxListSizeRatio and MinListSizeRatio, which are relative to the mean list
+// size of the training data.
+func (index *InvertedFileIndex[T1, T2]) balance(data []float32, config clusterTrainConfig) error {
+	numVectors := len(data) / index.state.NumFeatures
+	assignments := make([]int, numVectors)
+	err := index.cluster.Predict(data, func(row int, minCol int, minVal float32) error {
+		assignments[row] = minCol
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	meanSize := float64(numVectors) / float64(index.state.NumClusters)
+	maxSize := int(math.Ceil(float64(index.state.Config.MaxListSizeRatio) * meanSize))
+	minSize := int(math.Floor(float64(index.state.Config.MinListSizeRatio) * meanSize))
+	centroids, _, err := balanceClusters(
+		data,
+		index.state.NumFeatures,
+		index.cluster.Centroids(),
+		assignments,
+		maxSize,
+		minSize,
+		config,
+	)
+	if err != nil {
+		return err
+	}
+	return setCentroids(index.cluster, centroids)
+}
+
+// predict assigns each vector to its nearest coarse centroid, using the HNSW
+// quantizer when one was configured.
+func (index *InvertedFileIndex[T1, T2]) predict(data []float32, fn func(row int, minCol int, minVal float32) error) error {
+	if index.quantizer != nil {
+		return index.quantizer.Predict(data, index.state.NumFeatures, fn)
+	}
+	return index.cluster.Predict(data, fn)
+}
+
 type subFlatIndexBuilder struct{}
 
-func (b *subFlatIndexBuilder) build(numFeatures int) (ANNIndex, error) {
+func (b *subFlatIndexBuilder) build(numFeatures int, _ int) (ANNIndex, error) {
 	return newFlatIndex(numFeatures)
 }
 
@@ -341,8 +949,14 @@ type subPQIndexBuilder[T CodeType] struct {
 	numSubspaces int
 	numClusters  T
 	opts         []ProductQuantizationIndexOption
+	seed         uint64
+	isSeeded     bool
 }
 
-func (b *subPQIndexBuilder[T]) build(numFeatures int) (ANNIndex, error) {
-	return newProductQuantizationIndex(numFeatures, b.numSubspaces, b.numClusters, b.opts...)
+func (b *subPQIndexBuilder[T]) build(numFeatures int, list int) (ANNIndex, error) {
+	opts := b.opts
+	if b.isSeeded {
+		opts = append(opts[:len(opts):len(opts)], WithPQSeed(subSeed(b.seed, list)))
+	}
+	return newProductQuantizationIndex(numFeatures, b.numSubspaces, b.numClusters, opts...)
 }
@@ -1,9 +1,9 @@
 package vanadium_index
 
-type InvertedFileIndexOption func(*InvertedFileIndexConfig, []ProductQuantizationIndexOption) error
+type InvertedFileIndexOption func(*InvertedFileIndexConfig, *[]ProductQuantizationIndexOption) error
 
 func WithIVFMaxIterations(maxIterations int) InvertedFileIndexOption {
-	return func(config *InvertedFileIndexConfig, _ []ProductQuantizationIndexOption) error {
+	return func(config *InvertedFileIndexConfig, _ *[]ProductQuantizationIndexOption) error {
 		if maxIterations <= 0 {
 			return ErrInvalidNumIterations
 		}
@@ -13,7 +13,7 @@ func WithIVFMaxIterations(maxIterations int) InvertedFileIndexOption {
 }
 
 func WithIVFTolerance(tol float32) InvertedFileIndexOption {
-	return func(config *InvertedFileIndexConfig, _ []ProductQuantizationIndexOption) error {
+	return func(config *InvertedFileIndexConfig, _ *[]ProductQuantizationIndexOption) error {
 		if tol <= 0 {
 			return ErrInvalidTol
 		}
@@ -22,12 +22,100 @@ func WithIVFTolerance(tol float32) InvertedFileIndexOption {
 	}
 }
 
+func WithIVFHNSWQuantizer(m, efConstruction, efSearch int) InvertedFileIndexOption {
+	return func(config *InvertedFileIndexConfig, _ *[]ProductQuantizationIndexOption) error {
+		if m <= 0 || efConstruction <= 0 || efSearch <= 0 {
+			return ErrInvalidHNSWParameters
+		}
+		config.HNSWM = m
+		config.HNSWEfConstruction = efConstruction
+		config.HNSWEfSearch = efSearch
+		return nil
+	}
+}
+
+// WithIVFBalancedLists rebalances the coarse clustering after training so that
+// no list holds more than maxRatio times the mean list size, and lists smaller
+// than minRatio times the mean are merged into their neighbors. The sizes are
+// those of the training data. Balancing makes a bounded number of splits, so
+// ratios close to 1 may not be reached.
+func WithIVFBalancedLists(maxRatio, minRatio float32) InvertedFileIndexOption {
+	return func(config *InvertedFileIndexConfig, _ *[]ProductQuantizationIndexOption) error {
+		if maxRatio < 1 || minRatio < 0 || minRatio >= 1 {
+			return ErrInvalidListSizeRatio
+		}
+		config.MaxListSizeRatio = maxRatio
+		config.MinListSizeRatio = minRatio
+		return nil
+	}
+}
+
+// WithIVFTrainSampleSize limits training of the coarse quantizer and the
+// per-list indexes to a random sample of sampleSize vectors. Add still
+// ingests every vector.
+func WithIVFTrainSampleSize(sampleSize int) InvertedFileIndexOption {
+	return func(config *InvertedFileIndexConfig, _ *[]ProductQuantizationIndexOption) error {
+		if sampleSize <= 0 {
+			return ErrInvalidSampleSize
+		}
+		config.TrainSampleSize = sampleSize
+		return nil
+	}
+}
+
+// WithIVFSeed makes training reproducible. The seed drives sampling, the
+// initialization of the coarse kmeans and, for IVF-PQ, the per-list PQ
+// training. Seeded kmeans runs on a single goroutine per model.
+func WithIVFSeed(seed uint64) InvertedFileIndexOption {
+	return func(config *InvertedFileIndexConfig, _ *[]ProductQuantizationIndexOption) error {
+		config.Seed = seed
+		config.IsSeeded = true
+		return nil
+	}
+}
+
 func WithIVFPQIndex(opts ...ProductQuantizationIndexOption) InvertedFileIndexOption {
-	return func(config *InvertedFileIndexConfig, pqOpts []ProductQuantizationIndexOption) error {
+	return func(config *InvertedFileIndexConfig, pqOpts *[]ProductQuantizationIndexOption) error {
 		if pqOpts == nil {
 			return ErrInvalidPQOptions
 		}
-		pqOpts = append(pqOpts, opts...)
+		*pqOpts = append(*pqOpts, opts...)
+		return nil
+	}
+}
+
+func WithIVFInitMethod(method InitMethod) InvertedFileIndexOption {
+	return func(config *InvertedFileIndexConfig, _ *[]ProductQuantizationIndexOption) error {
+		if method != InitKMeansPlusPlus && method != InitRandom {
+			return ErrInvalidInitMethod
+		}
+		config.InitMethod = method
+		return nil
+	}
+}
+
+// WithIVFMiniBatch trains with mini-batch kmeans, sampling batchSize
+// vectors in each of numIterations iterations instead of running full-batch
+// Lloyd iterations over the whole training data.
+func WithIVFMiniBatch(batchSize, numIterations int) InvertedFileIndexOption {
+	return func(config *InvertedFileIndexConfig, _ *[]ProductQuantizationIndexOption) error {
+		if batchSize <= 0 {
+			return ErrInvalidBatchSize
+		}
+		if numIterations <= 0 {
+			return ErrInvalidNumIterations
+		}
+		config.MiniBatchSize = batchSize
+		config.MiniBatchIterations = numIterations
+		return nil
+	}
+}
+
+// WithIVFTrainingCallback registers fn to receive the TrainingReport at the
+// end of every Train. The callback is not saved with the index.
+func WithIVFTrainingCallback(fn TrainingCallback) InvertedFileIndexOption {
+	return func(config *InvertedFileIndexConfig, _ *[]ProductQuantizationIndexOption) error {
+		config.TrainingCallback = fn
 		return nil
 	}
 }
@@ -3,6 +3,9 @@ package vanadium_index
 import (
 	"bytes"
 	"encoding/gob"
+	"errors"
+	"math/rand/v2"
+	"reflect"
 	"testing"
 )
 
@@ -327,3 +330,438 @@ func TestInvertedFileIndexWithPQIndexSaveLoad(t *testing.T) {
 		}
 	}
 }
+
+func TestInvertedFileIndexWithHNSWQuantizer(t *testing.T) {
+	numFeatures := 4
+	numClusters := uint8(4)
+	index, err := newInvertedFileFlatIndex(
+		numFeatures,
+		numClusters,
+		WithIVFMaxIterations(10),
+		WithIVFHNSWQuantizer(4, 8, 8),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	data := []float32{
+		0.1, 0.2, 0.3, 0.4,
+		0.5, 0.6, 0.7, 0.8,
+		0.9, 1.0, 1.1, 1.2,
+		1.3, 1.4, 1.5, 1.6,
+	}
+
+	err = index.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+
+	err = index.Add(data)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	err = index.Save(enc)
+	if err != nil {
+		t.Fatalf("Failed to encode index: %v", err)
+	}
+
+	dec := gob.NewDecoder(&buf)
+	annIndex, err := LoadIndex(dec)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	index2, ok := annIndex.(*InvertedFileIndex[uint8, uint8])
+	if !ok {
+		t.Fatalf("loaded index is not a InvertedFileIndex")
+	}
+
+	if index2.quantizer == nil {
+		t.Fatalf("loaded index has no hnsw quantizer")
+	}
+
+	for _, idx := range []*InvertedFileIndex[uint8, uint8]{index, index2} {
+		results, distances, err := idx.Search(data, 1)
+		if err != nil {
+			t.Fatalf("Failed to search index: %v", err)
+		}
+
+		for i, result := range results {
+			if result[0] != i {
+				t.Fatalf("result[%d] = %d, expected %d", i, result[0], i)
+			}
+		}
+
+		for i, distance := range distances {
+			if distance[0] != 0 {
+				t.Fatalf("distance[%d] = %f, expected 0", i, distance[0])
+			}
+		}
+	}
+}
+
+func TestInvertedFileIndexWithBalancedLists(t *testing.T) {
+	numFeatures := 2
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 0, 1000*numFeatures)
+	for range 900 {
+		data = append(data, rng.Float32()*0.1, rng.Float32()*0.1)
+	}
+	for range 100 {
+		data = append(data, rng.Float32()*10, rng.Float32()*10)
+	}
+
+	listSizeStats := func(opts ...InvertedFileIndexOption) ListSizeStats {
+		t.Helper()
+		index, err := newInvertedFileFlatIndex(numFeatures, uint8(10), append(opts, WithIVFSeed(1))...)
+		if err != nil {
+			t.Fatalf("Failed to create index: %v", err)
+		}
+		err = index.Train(data)
+		if err != nil {
+			t.Fatalf("Failed to train index: %v", err)
+		}
+		err = index.Add(data)
+		if err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+		return index.ListSizeStats()
+	}
+
+	unbalanced := listSizeStats()
+	stats := listSizeStats(WithIVFBalancedLists(2, 0.2))
+	if stats.TotalCount != 1000 {
+		t.Fatalf("stats.TotalCount = %d, expected 1000", stats.TotalCount)
+	}
+	if float32(stats.Max) > 2*stats.Mean {
+		t.Fatalf("stats.Max = %d, expected <= %f", stats.Max, 2*stats.Mean)
+	}
+	if float32(stats.Min) < 0.2*stats.Mean {
+		t.Fatalf("stats.Min = %d, expected >= %f", stats.Min, 0.2*stats.Mean)
+	}
+	if float32(unbalanced.Max) <= 2*unbalanced.Mean || stats.StdDev >= unbalanced.StdDev {
+		t.Fatalf("expected balancing to even out %+v, got %+v", unbalanced, stats)
+	}
+}
+
+func TestInvertedFileIndexListSizeStats(t *testing.T) {
+	index, _ := newInvertedFileFlatIndex(2, uint8(4))
+	index.state.Mapping = [][]int{{0, 1, 2}, {3}, {}, {4, 5, 6, 7}}
+
+	sizes := index.ListSizes()
+	expected := []int{3, 1, 0, 4}
+	for i := range expected {
+		if sizes[i] != expected[i] {
+			t.Fatalf("sizes[%d] = %d, expected %d", i, sizes[i], expected[i])
+		}
+	}
+
+	stats := index.ListSizeStats()
+	if stats.NumLists != 4 || stats.NumEmpty != 1 || stats.Min != 0 || stats.Max != 4 || stats.Mean != 2 || stats.Imbalance != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestInvertedFileIndexWithPQIndexSeed(t *testing.T) {
+	numFeatures := 4
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 400*numFeatures)
+	for i := range data {
+		data[i] = rng.Float32()
+	}
+
+	indexes := make([]*InvertedFileIndex[uint8, uint8], 2)
+	for i := range indexes {
+		index, err := newInvertedFilePQIndex(numFeatures, uint8(4), 2, uint8(4),
+			WithIVFSeed(42),
+			WithIVFPQIndex(WithPQMaxIterations(20)),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create index: %v", err)
+		}
+		err = index.Train(data)
+		if err != nil {
+			t.Fatalf("Failed to train index: %v", err)
+		}
+		indexes[i] = index
+	}
+
+	centroids1 := indexes[0].cluster.Centroids()
+	centroids2 := indexes[1].cluster.Centroids()
+	for i := range centroids1 {
+		for j := range centroids1[i] {
+			if centroids1[i][j] != centroids2[i][j] {
+				t.Fatalf("centroids mismatch: %v != %v", centroids1[i][j], centroids2[i][j])
+			}
+		}
+	}
+
+	for c := range indexes[0].indexes {
+		pq1 := indexes[0].indexes[c].(*ProductQuantizationIndex[uint8])
+		pq2 := indexes[1].indexes[c].(*ProductQuantizationIndex[uint8])
+		if pq1.state.Config.MaxIterations != 20 {
+			t.Fatalf("pq1.state.Config.MaxIterations = %d, expected 20", pq1.state.Config.MaxIterations)
+		}
+		for m := range pq1.state.Codebooks {
+			for k := range pq1.state.Codebooks[m] {
+				for d := range pq1.state.Codebooks[m][k] {
+					if pq1.state.Codebooks[m][k][d] != pq2.state.Codebooks[m][k][d] {
+						t.Fatalf("codebook mismatch in list %d: %v != %v", c, pq1.state.Codebooks[m][k][d], pq2.state.Codebooks[m][k][d])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestInvertedFileIndexTrainingCallback(t *testing.T) {
+	numFeatures := 4
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 400*numFeatures)
+	for i := range data {
+		data[i] = rng.Float32()
+	}
+
+	var report *TrainingReport
+	index, err := newInvertedFilePQIndex(numFeatures, uint8(4), 2, uint8(4),
+		WithIVFTrainingCallback(func(r *TrainingReport) {
+			report = r
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+
+	err = index.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+
+	if report == nil {
+		t.Fatalf("training callback was not called")
+	}
+	if report.Coarse == nil || report.Coarse.Iterations <= 0 {
+		t.Fatalf("report.Coarse = %+v, expected a coarse report", report.Coarse)
+	}
+	if len(report.Lists) != 4 {
+		t.Fatalf("len(report.Lists) = %d, expected 4", len(report.Lists))
+	}
+	numVectors := 0
+	for c, list := range report.Lists {
+		if list == nil || len(list.Subspaces) != 2 {
+			t.Fatalf("report.Lists[%d] = %+v, expected a PQ report", c, list)
+		}
+		numVectors += list.NumVectors
+	}
+	if numVectors != 400 {
+		t.Fatalf("total list vectors = %d, expected 400", numVectors)
+	}
+	if report.MSE <= 0 {
+		t.Fatalf("report.MSE = %f, expected > 0", report.MSE)
+	}
+}
+
+func TestInvertedFileIndexWithPQIndexUndersizedList(t *testing.T) {
+	numFeatures := 4
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 0, 101*numFeatures)
+	for range 100 {
+		data = append(data, rng.Float32(), rng.Float32(), rng.Float32(), rng.Float32())
+	}
+	outlier := []float32{100, 100, 100, 100}
+	data = append(data, outlier...)
+
+	var report *TrainingReport
+	index, err := newInvertedFilePQIndex(numFeatures, uint8(2), 2, uint8(4),
+		WithIVFSeed(1),
+		WithIVFTrainingCallback(func(r *TrainingReport) {
+			report = r
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+
+	err = index.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+
+	if len(report.FallbackLists) != 1 {
+		t.Fatalf("report.FallbackLists = %v, expected one list", report.FallbackLists)
+	}
+	fallback := report.FallbackLists[0]
+	if report.Lists[fallback] == nil || report.Lists[fallback].NumVectors != 1 {
+		t.Fatalf("report.Lists[%d] = %+v, expected a report of one vector", fallback, report.Lists[fallback])
+	}
+
+	err = index.Add(data)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	results, _, err := index.Search(outlier, 1)
+	if err != nil {
+		t.Fatalf("Failed to search index: %v", err)
+	}
+	if results[0][0] != 100 {
+		t.Fatalf("results[0][0] = %d, expected 100", results[0][0])
+	}
+}
+
+func TestInvertedFileIndexFallbackInitMethod(t *testing.T) {
+	index, err := newInvertedFilePQIndex(4, uint8(2), 2, uint8(4),
+		WithIVFPQIndex(WithPQInitMethod(InitRandom)),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	template := index.indexes[0].(*ProductQuantizationIndex[uint8])
+	fallback, err := template.untrainedCopy()
+	if err != nil {
+		t.Fatalf("Failed to copy index: %v", err)
+	}
+	if fallback.state.Config.InitMethod != InitRandom {
+		t.Fatalf("expected %v, got %v", InitRandom, fallback.state.Config.InitMethod)
+	}
+	for i, cluster := range fallback.clusters {
+		state, err := clusterState(cluster)
+		if err != nil {
+			t.Fatalf("Failed to read cluster state: %v", err)
+		}
+		if state.InitMethod != InitRandom.kmeansInitMethod() {
+			t.Fatalf("cluster %d uses init method %d, expected %d", i, state.InitMethod, InitRandom.kmeansInitMethod())
+		}
+	}
+}
+
+func BenchmarkInvertedFilePQIndexAddOneByOne(b *testing.B) {
+	numFeatures := 16
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 1024*numFeatures)
+	for i := range data {
+		data[i] = rng.Float32()
+	}
+
+	index, err := NewIndex(numFeatures, AsIVFPQ(4, 4, 16, WithIVFMaxIterations(10), WithIVFSeed(1)))
+	if err != nil {
+		b.Fatalf("Failed to create index: %v", err)
+	}
+	err = index.Train(data)
+	if err != nil {
+		b.Fatalf("Failed to train index: %v", err)
+	}
+
+	b.ResetTimer()
+	for i := range b.N {
+		n := i % 1024
+		err = index.Add(data[n*numFeatures : (n+1)*numFeatures])
+		if err != nil {
+			b.Fatalf("Failed to add data: %v", err)
+		}
+	}
+}
+
+func TestInvertedFileIndexMerge(t *testing.T) {
+	numFeatures := 4
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 64*numFeatures)
+	for i := range data {
+		data[i] = rng.Float32()
+	}
+
+	for _, builder := range []IndexBuilder{
+		AsIVFFlat(4, WithIVFMaxIterations(10), WithIVFSeed(1)),
+		AsIVFPQ(4, 2, 2, WithIVFMaxIterations(10), WithIVFSeed(1)),
+	} {
+		model, err := NewIndex(numFeatures, builder)
+		if err != nil {
+			t.Fatalf("Failed to create index: %v", err)
+		}
+		err = model.Train(data)
+		if err != nil {
+			t.Fatalf("Failed to train index: %v", err)
+		}
+		testMerge(t, model, data, numFeatures)
+	}
+
+	model, _ := NewIndex(numFeatures, AsIVFFlat(4, WithIVFMaxIterations(10), WithIVFSeed(1)))
+	other, _ := NewIndex(numFeatures, AsIVFFlat(4, WithIVFMaxIterations(10), WithIVFSeed(2)))
+	for _, index := range []ANNIndex{model, other} {
+		err := index.Train(data)
+		if err != nil {
+			t.Fatalf("Failed to train index: %v", err)
+		}
+		err = index.Add(data)
+		if err != nil {
+			t.Fatalf("Failed to add data: %v", err)
+		}
+	}
+	err := model.Merge(other)
+	if !errors.Is(err, ErrIncompatibleIndex) {
+		t.Fatalf("expected %v, got %v", ErrIncompatibleIndex, err)
+	}
+}
+
+func TestInvertedFilePQIndexEncodeDecode(t *testing.T) {
+	numFeatures := 4
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 128*numFeatures)
+	for i := range data {
+		data[i] = rng.Float32()
+	}
+	query := data[:4*numFeatures]
+
+	index, _ := newInvertedFilePQIndex[uint8, uint8](numFeatures, 4, 2, 4, WithIVFMaxIterations(10), WithIVFSeed(1))
+	err := index.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+	err = index.Add(data)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	lists, codes, err := index.Encode(data)
+	if err != nil {
+		t.Fatalf("Failed to encode data: %v", err)
+	}
+	for c, ids := range index.state.Mapping {
+		for _, id := range ids {
+			if lists[id] != c {
+				t.Fatalf("expected vector %d in list %d, got %d", id, c, lists[id])
+			}
+		}
+	}
+
+	results, distances, _ := index.Search(query, 5)
+	codeResults, codeDistances, err := index.SearchCodes(query, lists, codes, 5)
+	if err != nil {
+		t.Fatalf("Failed to search codes: %v", err)
+	}
+	if !reflect.DeepEqual(results, codeResults) || !reflect.DeepEqual(distances, codeDistances) {
+		t.Fatalf("SearchCodes results differ from Search")
+	}
+
+	decoded, err := index.Decode(lists, codes)
+	if err != nil {
+		t.Fatalf("Failed to decode codes: %v", err)
+	}
+	r := results[0][0]
+	expected := squaredEuclideanDistance(query[:numFeatures], decoded[r*numFeatures:(r+1)*numFeatures])
+	if diff := expected - distances[0][0]; diff > 1e-5 || diff < -1e-5 {
+		t.Fatalf("expected distance %f, got %f", expected, distances[0][0])
+	}
+
+	_, err = index.Decode([]int{4}, []uint8{0, 0})
+	if !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("expected %v, got %v", ErrInvalidCode, err)
+	}
+
+	flat, _ := newInvertedFileFlatIndex[uint8](numFeatures, 4)
+	_, _, err = flat.Encode(data)
+	if !errors.Is(err, ErrNoCodes) {
+		t.Fatalf("expected %v, got %v", ErrNoCodes, err)
+	}
+}
@@ -8,9 +8,14 @@ import (
 type IndexType string
 
 const (
-	IndexTypeFlat IndexType = "flat"
-	IndexTypePQ   IndexType = "pq"
-	IndexTypeIVF  IndexType = "ivf"
+	IndexTypeFlat    IndexType = "flat"
+	IndexTypePQ      IndexType = "pq"
+	IndexTypeIVF     IndexType = "ivf"
+	IndexTypeRefine  IndexType = "refine"
+	IndexTypeSharded IndexType = "sharded"
+
+	IndexTypeBinaryFlat IndexType = "binary_flat"
+	IndexTypeBinaryIVF  IndexType = "binary_ivf"
 )
 
 type CodeTypeName string
@@ -37,6 +42,8 @@ func LoadIndex(dec *gob.Decoder) (ANNIndex, error) {
 	switch meta.IndexType {
 	case IndexTypeFlat:
 		return loadFlatIndex(dec)
+	case IndexTypeSharded:
+		return loadShardedIndex(dec)
 	case IndexTypePQ:
 		switch meta.CodeType1 {
 		case CodeTypeNameUint8:
@@ -46,7 +53,7 @@ func LoadIndex(dec *gob.Decoder) (ANNIndex, error) {
 		case CodeTypeNameUint32:
 			return loadProductQuantizationIndex[uint32](dec)
 		default:
-			return nil, fmt.Errorf("unknown code type: %s", meta.CodeType1)
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCodeType, meta.CodeType1)
 		}
 	case IndexTypeIVF:
 		switch meta.CodeType1 {
@@ -59,7 +66,7 @@ func LoadIndex(dec *gob.Decoder) (ANNIndex, error) {
 			case CodeTypeNameUint32:
 				return loadInvertedFile[uint8, uint32](dec)
 			default:
-				return nil, fmt.Errorf("unknown code type: %s", meta.CodeType2)
+				return nil, fmt.Errorf("%w: %s", ErrUnknownCodeType, meta.CodeType2)
 			}
 		case CodeTypeNameUint16:
 			switch meta.CodeType2 {
@@ -70,7 +77,7 @@ func LoadIndex(dec *gob.Decoder) (ANNIndex, error) {
 			case CodeTypeNameUint32:
 				return loadInvertedFile[uint16, uint32](dec)
 			default:
-				return nil, fmt.Errorf("unknown code type: %s", meta.CodeType2)
+				return nil, fmt.Errorf("%w: %s", ErrUnknownCodeType, meta.CodeType2)
 			}
 		case CodeTypeNameUint32:
 			switch meta.CodeType2 {
@@ -81,10 +88,36 @@ func LoadIndex(dec *gob.Decoder) (ANNIndex, error) {
 			case CodeTypeNameUint32:
 				return loadInvertedFile[uint32, uint32](dec)
 			default:
-				return nil, fmt.Errorf("unknown code type: %s", meta.CodeType2)
+				return nil, fmt.Errorf("%w: %s", ErrUnknownCodeType, meta.CodeType2)
 			}
 		}
-		return nil, fmt.Errorf("unknown code type: %s", meta.CodeType1)
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCodeType, meta.CodeType1)
+	case IndexTypeRefine:
+		return loadRefineIndex(dec)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownIndexType, meta.IndexType)
+}
+
+func LoadBinaryIndex(dec *gob.Decoder) (BinaryANNIndex, error) {
+	var meta MetaData
+	err := dec.Decode(&meta)
+	if err != nil {
+		return nil, err
+	}
+	switch meta.IndexType {
+	case IndexTypeBinaryFlat:
+		return loadBinaryFlatIndex(dec)
+	case IndexTypeBinaryIVF:
+		switch meta.CodeType1 {
+		case CodeTypeNameUint8:
+			return loadBinaryInvertedFileIndex[uint8](dec)
+		case CodeTypeNameUint16:
+			return loadBinaryInvertedFileIndex[uint16](dec)
+		case CodeTypeNameUint32:
+			return loadBinaryInvertedFileIndex[uint32](dec)
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCodeType, meta.CodeType1)
+		}
 	}
-	return nil, fmt.Errorf("unknown index type: %s", meta.IndexType)
+	return nil, fmt.Errorf("%w: %s", ErrUnknownIndexType, meta.IndexType)
 }
@@ -0,0 +1,49 @@
+package vanadium_index
+
+import "io"
+
+// NewIndexFromModel returns an empty index with a copy of the trained model
+// of index: the coarse centroids of IVF, the codebooks of PQ and the scalar
+// quantizer of Refine. The vectors of index are left out, so the new index is
+// ready for Add without training.
+func NewIndexFromModel(index ANNIndex) (ANNIndex, error) {
+	info := index.Info()
+	if !info.IsTrained {
+		return nil, newIndexError(OpNew, info.IndexType, ErrNotTrained)
+	}
+	return index.emptyCopy()
+}
+
+// WriteModel saves the trained model of index to w, without its vectors. The
+// model loads with ReadIndex as an empty index.
+func WriteModel(w io.Writer, index ANNIndex, opts ...SaveOption) error {
+	model, err := NewIndexFromModel(index)
+	if err != nil {
+		return err
+	}
+	return WriteIndex(w, model, opts...)
+}
+
+// SaveModelFile writes the trained model of index to path atomically like
+// SaveFile.
+func SaveModelFile(path string, index ANNIndex, opts ...SaveOption) error {
+	model, err := NewIndexFromModel(index)
+	if err != nil {
+		return err
+	}
+	return SaveFile(path, model, opts...)
+}
+
+// LoadModelFile loads the trained model saved in path by SaveModelFile or
+// SaveFile, and returns it as an empty index. A full index file is loaded
+// completely before its vectors are dropped.
+func LoadModelFile(path string) (ANNIndex, error) {
+	index, err := LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if index.NumVectors() == 0 {
+		return index, nil
+	}
+	return NewIndexFromModel(index)
+}
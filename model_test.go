@@ -0,0 +1,145 @@
+package vanadium_index
+
+import (
+	"errors"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveModelFileLoadModelFile(t *testing.T) {
+	numFeatures := 4
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 512*numFeatures)
+	for i := range data {
+		data[i] = rng.Float32()
+	}
+	query := data[:8*numFeatures]
+
+	builders := map[string]IndexBuilder{
+		"Flat":  AsFlat(),
+		"PQ":    AsPQ(2, 16, WithPQMaxIterations(5), WithPQSeed(1)),
+		"IVFPQ": AsIVFPQ(4, 2, 16, WithIVFMaxIterations(5), WithIVFSeed(1)),
+		"IVFPQ_HNSW": AsIVFPQ(4, 2, 16, WithIVFMaxIterations(5), WithIVFSeed(1),
+			WithIVFHNSWQuantizer(4, 16, 8)),
+		"Refine":  AsRefine(AsIVFFlat(4, WithIVFSeed(1)), 2, WithRefineScalarQuantizer()),
+		"Sharded": AsSharded(2, AsPQ(2, 16, WithPQMaxIterations(5), WithPQSeed(1))),
+	}
+	for name, builder := range builders {
+		t.Run(name, func(t *testing.T) {
+			index, _ := NewIndex(numFeatures, builder)
+			err := index.Train(data)
+			if err != nil {
+				t.Fatalf("Failed to train index: %v", err)
+			}
+			err = index.Add(data)
+			if err != nil {
+				t.Fatalf("Failed to add data: %v", err)
+			}
+
+			dir := t.TempDir()
+			indexPath := filepath.Join(dir, "index")
+			modelPath := filepath.Join(dir, "model")
+			err = SaveFile(indexPath, index)
+			if err != nil {
+				t.Fatalf("Failed to save index: %v", err)
+			}
+			err = SaveModelFile(modelPath, index)
+			if err != nil {
+				t.Fatalf("Failed to save model: %v", err)
+			}
+			indexStat, _ := os.Stat(indexPath)
+			modelStat, _ := os.Stat(modelPath)
+			if modelStat.Size() >= indexStat.Size() {
+				t.Fatalf("expected model size %d to be less than index size %d", modelStat.Size(), indexStat.Size())
+			}
+
+			for _, path := range []string{modelPath, indexPath} {
+				model, err := LoadModelFile(path)
+				if err != nil {
+					t.Fatalf("Failed to load model: %v", err)
+				}
+				if model.NumVectors() != 0 || !model.Info().IsTrained {
+					t.Fatalf("expected an empty trained index, got %d vectors", model.NumVectors())
+				}
+				err = model.Add(data)
+				if err != nil {
+					t.Fatalf("Failed to add data: %v", err)
+				}
+				results, distances, _ := index.Search(query, 5)
+				modelResults, modelDistances, err := model.Search(query, 5)
+				if err != nil {
+					t.Fatalf("Failed to search: %v", err)
+				}
+				if !reflect.DeepEqual(results, modelResults) || !reflect.DeepEqual(distances, modelDistances) {
+					t.Fatalf("search results differ between the index and its model")
+				}
+			}
+		})
+	}
+}
+
+func TestNewIndexFromModel(t *testing.T) {
+	numFeatures := 4
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 128*numFeatures)
+	for i := range data {
+		data[i] = rng.Float32()
+	}
+
+	untrained, _ := NewIndex(numFeatures, AsPQ(2, 4))
+	_, err := NewIndexFromModel(untrained)
+	if !errors.Is(err, ErrNotTrained) {
+		t.Fatalf("expected %v, got %v", ErrNotTrained, err)
+	}
+
+	model, _ := NewIndex(numFeatures, AsIVFPQ(2, 2, 4, WithIVFMaxIterations(5), WithIVFSeed(1)))
+	err = model.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+
+	// Shards built from the model share it and can be searched right away.
+	sharded, err := NewIndex(numFeatures, AsSharded(2, FromModel(model)))
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	err = sharded.Add(data)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	err = model.Add(data)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	results, _, _ := model.Search(data[:numFeatures], 1)
+	shardedResults, _, err := sharded.Search(data[:numFeatures], 1)
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if !reflect.DeepEqual(results, shardedResults) {
+		t.Fatalf("expected %v, got %v", results, shardedResults)
+	}
+
+	// Retraining a copy leaves the model untouched.
+	modelIVF := model.(*InvertedFileIndex[uint8, uint8])
+	centroids := modelIVF.cluster.Centroids()
+	copied, _ := NewIndexFromModel(model)
+	err = copied.Train(data[:64*numFeatures])
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+	if !equalCentroids(modelIVF.cluster.Centroids(), centroids) {
+		t.Fatalf("model changed after training a copy")
+	}
+	if equalCentroids(copied.(*InvertedFileIndex[uint8, uint8]).cluster.Centroids(), centroids) {
+		t.Fatalf("expected retrained copy to have different centroids")
+	}
+
+	_, err = NewIndex(numFeatures+1, FromModel(model))
+	if !errors.Is(err, ErrIncompatibleIndex) {
+		t.Fatalf("expected %v, got %v", ErrIncompatibleIndex, err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/gob"
 	"reflect"
 	"runtime"
+	"slices"
 
 	"github.com/monochromegane/kmeans"
 	"golang.org/x/sync/errgroup"
@@ -12,6 +13,7 @@ import (
 type ProductQuantizationIndex[T CodeType] struct {
 	state    *ProductQuantizationState[T]
 	clusters []*kmeans.KMeans
+	report   *TrainingReport
 }
 
 type ProductQuantizationState[T CodeType] struct {
@@ -27,8 +29,15 @@ type ProductQuantizationState[T CodeType] struct {
 }
 
 type ProductQuantizationIndexConfig struct {
-	MaxIterations int
-	Tolerance     float32
+	MaxIterations       int
+	Tolerance           float32
+	TrainSampleSize     int
+	Seed                uint64
+	IsSeeded            bool
+	InitMethod          InitMethod
+	MiniBatchSize       int
+	MiniBatchIterations int
+	TrainingCallback    TrainingCallback
 }
 
 func newProductQuantizationIndex[T CodeType](
@@ -40,7 +49,7 @@ func newProductQuantizationIndex[T CodeType](
 		return nil, ErrInvalidNumFeatures
 	}
 	if numSubspaces <= 0 || numSubspaces > numFeatures || numFeatures%numSubspaces != 0 {
-		return nil, ErrInvalidNumSubspaces
+		return nil, newIndexError(OpNew, IndexTypePQ, ErrInvalidNumSubspaces, "numSubspaces", numSubspaces, "numFeatures", numFeatures)
 	}
 	numSubFeatures := numFeatures / numSubspaces
 
@@ -71,12 +80,18 @@ func newProductQuantizationIndex[T CodeType](
 			return nil, err
 		}
 	}
+	if index.state.Config.TrainSampleSize > 0 && index.state.Config.TrainSampleSize < int(numClusters) {
+		return nil, newIndexError(
+			OpNew, IndexTypePQ, ErrInvalidSampleSize,
+			"required", int(numClusters), "actual", index.state.Config.TrainSampleSize,
+		)
+	}
 
 	for i := range index.state.NumSubspaces {
 		cluster, err := kmeans.NewKMeans(
 			int(index.state.NumClusters),
 			numSubFeatures,
-			kmeans.WithInitMethod(kmeans.INIT_KMEANS_PLUS_PLUS),
+			kmeans.WithInitMethod(index.state.Config.InitMethod.kmeansInitMethod()),
 		)
 		if err != nil {
 			return nil, err
@@ -98,17 +113,29 @@ func loadProductQuantizationIndex[T CodeType](dec *gob.Decoder) (*ProductQuantiz
 
 func (index *ProductQuantizationIndex[T]) Train(data []float32) error {
 	if len(data) == 0 {
-		return ErrEmptyData
+		return newIndexError(OpTrain, IndexTypePQ, ErrEmptyData)
 	}
 
 	if len(data)%index.state.NumFeatures != 0 {
-		return ErrInvalidDataLength
+		return newIndexError(OpTrain, IndexTypePQ, ErrInvalidDataLength, "dataLength", len(data), "numFeatures", index.state.NumFeatures)
+	}
+
+	if numVectors := len(data) / index.state.NumFeatures; numVectors < int(index.state.NumClusters) {
+		return newIndexError(
+			OpTrain, IndexTypePQ, ErrInsufficientTrainingData,
+			"required", int(index.state.NumClusters), "actual", numVectors,
+		)
 	}
 
+	rng := newRand(index.state.Config.Seed, index.state.Config.IsSeeded)
+	data = sampleVectors(data, index.state.NumFeatures, index.state.Config.TrainSampleSize, rng)
+
 	var eg errgroup.Group
 	eg.SetLimit(runtime.NumCPU())
 
 	numVectors := len(data) / index.state.NumFeatures
+	subspaces := make([]ClusteringReport, index.state.NumSubspaces)
+	sumDists := make([]float32, index.state.NumSubspaces)
 
 	for i := range index.state.NumSubspaces {
 		eg.Go(func() error {
@@ -119,16 +146,32 @@ func (index *ProductQuantizationIndex[T]) Train(data []float32) error {
 				copy(subData[v*index.state.NumSubFeatures:], data[start:end])
 			}
 
-			_, _, err := index.clusters[i].Train(
-				subData,
-				kmeans.WithMaxIterations(index.state.Config.MaxIterations),
-				kmeans.WithTolerance(index.state.Config.Tolerance),
-			)
+			config := clusterTrainConfig{
+				maxIterations:       index.state.Config.MaxIterations,
+				tolerance:           index.state.Config.Tolerance,
+				miniBatchSize:       index.state.Config.MiniBatchSize,
+				miniBatchIterations: index.state.Config.MiniBatchIterations,
+			}
+			if index.state.Config.IsSeeded {
+				config.rng = newRand(subSeed(index.state.Config.Seed, i), true)
+			}
+			numIter, inertia, converged, err := trainCluster(index.clusters[i], subData, config)
 			if err != nil {
-				return err
+				return newIndexError(OpTrain, IndexTypePQ, err).withSubspace(i)
 			}
 			centroids := index.clusters[i].Centroids()
 			index.state.Codebooks[i] = centroids
+
+			counts := make([]int, int(index.state.NumClusters))
+			err = index.clusters[i].Predict(subData, func(row int, minCol int, minVal float32) error {
+				counts[minCol]++
+				sumDists[i] += minVal
+				return nil
+			})
+			if err != nil {
+				return newIndexError(OpTrain, IndexTypePQ, err).withSubspace(i)
+			}
+			subspaces[i] = newClusteringReport(numIter, inertia, converged, counts)
 			return nil
 		})
 	}
@@ -136,32 +179,136 @@ func (index *ProductQuantizationIndex[T]) Train(data []float32) error {
 		return err
 	}
 
+	sumDist := float32(0)
+	for _, dist := range sumDists {
+		sumDist += dist
+	}
+	index.report = &TrainingReport{
+		NumVectors: numVectors,
+		Subspaces:  subspaces,
+		MSE:        sumDist / float32(numVectors),
+	}
+	if index.state.Config.TrainingCallback != nil {
+		index.state.Config.TrainingCallback(index.report)
+	}
+
 	index.state.IsTrained = true
 	return nil
 }
 
+// untrainedCopy returns a new untrained index with the same configuration.
+func (index *ProductQuantizationIndex[T]) untrainedCopy() (*ProductQuantizationIndex[T], error) {
+	config := *index.state.Config
+	config.TrainingCallback = nil
+	// Build the copy from the configuration, so that its clusters use the
+	// configured init method.
+	return newProductQuantizationIndex(
+		index.state.NumFeatures,
+		index.state.NumSubspaces,
+		index.state.NumClusters,
+		func(c *ProductQuantizationIndexConfig) error {
+			*c = config
+			return nil
+		},
+	)
+}
+
+// shareModel makes index use the codebooks trained by other.
+func (index *ProductQuantizationIndex[T]) shareModel(other *ProductQuantizationIndex[T]) error {
+	for i := range index.state.NumSubspaces {
+		codebook := other.clusters[i].Centroids()
+		err := setCentroids(index.clusters[i], codebook)
+		if err != nil {
+			return err
+		}
+		index.state.Codebooks[i] = other.clusters[i].Centroids()
+	}
+	index.state.IsTrained = true
+	return nil
+}
+
+// quantizationError returns the sum of squared distances between each vector
+// of data and its reconstruction from the codebooks.
+func (index *ProductQuantizationIndex[T]) quantizationError(data []float32) (float32, error) {
+	numVectors := len(data) / index.state.NumFeatures
+	sumDist := float32(0)
+	subData := make([]float32, numVectors*index.state.NumSubFeatures)
+	for i := range index.state.NumSubspaces {
+		for v := range numVectors {
+			start := v*index.state.NumFeatures + i*index.state.NumSubFeatures
+			copy(subData[v*index.state.NumSubFeatures:], data[start:start+index.state.NumSubFeatures])
+		}
+		err := index.clusters[i].Predict(subData, func(row int, minCol int, minVal float32) error {
+			sumDist += minVal
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return sumDist, nil
+}
+
+func (index *ProductQuantizationIndex[T]) trainingReport() *TrainingReport {
+	return index.report
+}
+
 func (index *ProductQuantizationIndex[T]) Add(data []float32) error {
 	if len(data) == 0 {
-		return ErrEmptyData
+		return newIndexError(OpAdd, IndexTypePQ, ErrEmptyData)
+	}
+
+	if len(data)%index.state.NumFeatures != 0 {
+		return newIndexError(OpAdd, IndexTypePQ, ErrInvalidDataLength, "dataLength", len(data), "numFeatures", index.state.NumFeatures)
+	}
+
+	if !index.state.IsTrained {
+		return newIndexError(OpAdd, IndexTypePQ, ErrNotTrained)
+	}
+
+	numVectors := len(data) / index.state.NumFeatures
+	oldLength := index.state.NumVectors * index.state.NumSubspaces
+	newLength := oldLength + numVectors*index.state.NumSubspaces
+	index.state.Codes = slices.Grow(index.state.Codes[:oldLength], newLength-oldLength)[:newLength]
+
+	err := index.encodeInto(OpAdd, data, index.state.Codes[oldLength:])
+	if err != nil {
+		return err
+	}
+
+	index.state.NumVectors += numVectors
+	return nil
+}
+
+// Encode returns the codes of data, NumSubspaces codes per vector, as Add
+// would store them.
+func (index *ProductQuantizationIndex[T]) Encode(data []float32) ([]T, error) {
+	if len(data) == 0 {
+		return nil, newIndexError(OpEncode, IndexTypePQ, ErrEmptyData)
 	}
 
 	if len(data)%index.state.NumFeatures != 0 {
-		return ErrInvalidDataLength
+		return nil, newIndexError(OpEncode, IndexTypePQ, ErrInvalidDataLength, "dataLength", len(data), "numFeatures", index.state.NumFeatures)
 	}
 
 	if !index.state.IsTrained {
-		return ErrNotTrained
+		return nil, newIndexError(OpEncode, IndexTypePQ, ErrNotTrained)
+	}
+
+	codes := make([]T, len(data)/index.state.NumFeatures*index.state.NumSubspaces)
+	err := index.encodeInto(OpEncode, data, codes)
+	if err != nil {
+		return nil, err
 	}
+	return codes, nil
+}
 
+// encodeInto writes the codes of data into codes, one subspace per goroutine.
+func (index *ProductQuantizationIndex[T]) encodeInto(op string, data []float32, codes []T) error {
 	var eg errgroup.Group
 	eg.SetLimit(runtime.NumCPU())
 
 	numVectors := len(data) / index.state.NumFeatures
-	oldNumVectors := index.state.NumVectors
-	newCodes := make([]T, (oldNumVectors+numVectors)*index.state.NumSubspaces)
-	copy(newCodes, index.state.Codes)
-	index.state.Codes = newCodes
-
 	for i := range index.state.NumSubspaces {
 		eg.Go(func() error {
 			subData := make([]float32, numVectors*index.state.NumSubFeatures)
@@ -172,40 +319,105 @@ func (index *ProductQuantizationIndex[T]) Add(data []float32) error {
 			}
 
 			err := index.clusters[i].Predict(subData, func(row int, minCol int, minVal float32) error {
-				index.state.Codes[(oldNumVectors+row)*index.state.NumSubspaces+i] = T(minCol)
+				codes[row*index.state.NumSubspaces+i] = T(minCol)
 				return nil
 			})
 			if err != nil {
-				return err
+				return newIndexError(op, IndexTypePQ, err).withSubspace(i)
 			}
 			return nil
 		})
 	}
-	if err := eg.Wait(); err != nil {
-		return err
+	return eg.Wait()
+}
+
+// Decode returns the reconstruction of each vector of codes from the
+// codebooks.
+func (index *ProductQuantizationIndex[T]) Decode(codes []T) ([]float32, error) {
+	err := index.checkCodes(OpDecode, codes)
+	if err != nil {
+		return nil, err
 	}
 
-	index.state.NumVectors += numVectors
+	numVectors := len(codes) / index.state.NumSubspaces
+	data := make([]float32, 0, numVectors*index.state.NumFeatures)
+	for n := range numVectors {
+		for m := range index.state.NumSubspaces {
+			code := codes[n*index.state.NumSubspaces+m]
+			data = append(data, index.state.Codebooks[m][code]...)
+		}
+	}
+	return data, nil
+}
+
+// checkCodes validates codes supplied by the caller.
+func (index *ProductQuantizationIndex[T]) checkCodes(op string, codes []T) error {
+	if len(codes) == 0 {
+		return newIndexError(op, IndexTypePQ, ErrEmptyData)
+	}
+
+	if len(codes)%index.state.NumSubspaces != 0 {
+		return newIndexError(op, IndexTypePQ, ErrInvalidDataLength, "dataLength", len(codes), "numSubspaces", index.state.NumSubspaces)
+	}
+
+	if !index.state.IsTrained {
+		return newIndexError(op, IndexTypePQ, ErrNotTrained)
+	}
+
+	for i, code := range codes {
+		if code >= index.state.NumClusters {
+			return newIndexError(op, IndexTypePQ, ErrInvalidCode, "code", int(code), "numClusters", int(index.state.NumClusters)).withSubspace(i % index.state.NumSubspaces)
+		}
+	}
 	return nil
 }
 
 func (index *ProductQuantizationIndex[T]) Search(query []float32, k int) ([][]int, [][]float32, error) {
 	if k <= 0 {
-		return nil, nil, ErrInvalidK
+		return nil, nil, newIndexError(OpSearch, IndexTypePQ, ErrInvalidK, "k", k)
 	}
 
 	if len(query) == 0 {
-		return nil, nil, ErrEmptyData
+		return nil, nil, newIndexError(OpSearch, IndexTypePQ, ErrEmptyData)
 	}
 
 	if len(query)%index.state.NumFeatures != 0 {
-		return nil, nil, ErrInvalidDataLength
+		return nil, nil, newIndexError(OpSearch, IndexTypePQ, ErrInvalidDataLength, "dataLength", len(query), "numFeatures", index.state.NumFeatures)
 	}
 
 	if !index.state.IsTrained {
-		return nil, nil, ErrNotTrained
+		return nil, nil, newIndexError(OpSearch, IndexTypePQ, ErrNotTrained)
+	}
+
+	return index.searchCodes(query, index.state.Codes, index.state.NumVectors, k)
+}
+
+// SearchCodes searches codes supplied by the caller, for example codes
+// computed with Encode and stored elsewhere, with asymmetric distances from
+// the raw queries. Results are positions in codes, in vectors.
+func (index *ProductQuantizationIndex[T]) SearchCodes(query []float32, codes []T, k int) ([][]int, [][]float32, error) {
+	if k <= 0 {
+		return nil, nil, newIndexError(OpSearch, IndexTypePQ, ErrInvalidK, "k", k)
+	}
+
+	if len(query) == 0 {
+		return nil, nil, newIndexError(OpSearch, IndexTypePQ, ErrEmptyData)
+	}
+
+	if len(query)%index.state.NumFeatures != 0 {
+		return nil, nil, newIndexError(OpSearch, IndexTypePQ, ErrInvalidDataLength, "dataLength", len(query), "numFeatures", index.state.NumFeatures)
+	}
+
+	err := index.checkCodes(OpSearch, codes)
+	if err != nil {
+		return nil, nil, err
 	}
+	return index.searchCodes(query, codes, len(codes)/index.state.NumSubspaces, k)
+}
 
+// searchCodes ranks the first numVectors vectors of codes for each query
+// with a distance table per query.
+func (index *ProductQuantizationIndex[T]) searchCodes(query []float32, codes []T, numVectors int, k int) ([][]int, [][]float32, error) {
 	type distanceItem struct {
 		index    int
 		distance float32
@@ -237,10 +449,10 @@ func (index *ProductQuantizationIndex[T]) Search(query []float32, k int) ([][]in
 			return nil, nil, err
 		}
 
-		chunkSize := index.state.NumVectors / numWorkers
+		chunkSize := numVectors / numWorkers
 		if chunkSize == 0 {
 			chunkSize = 1
-			numWorkers = index.state.NumVectors
+			numWorkers = numVectors
 		}
 		distChan := make(chan []distanceItem, numWorkers)
 
@@ -248,14 +460,14 @@ func (index *ProductQuantizationIndex[T]) Search(query []float32, k int) ([][]in
 			start := w * chunkSize
 			end := start + chunkSize
 			if w == numWorkers-1 {
-				end = index.state.NumVectors
+				end = numVectors
 			}
 			eg.Go(func() error {
 				batchDistance := make([]distanceItem, 0, batchSize)
 				for n := start; n < end; n++ {
 					distance := float32(0)
 					for m := range index.state.NumSubspaces {
-						code := index.state.Codes[n*index.state.NumSubspaces+m]
+						code := codes[n*index.state.NumSubspaces+m]
 						distance += distanceTable[m*int(index.state.NumClusters)+int(code)]
 					}
 					batchDistance = append(batchDistance, distanceItem{index: n, distance: distance})
@@ -285,11 +497,12 @@ func (index *ProductQuantizationIndex[T]) Search(query []float32, k int) ([][]in
 	results := make([][]int, numQueries)
 	distances := make([][]float32, numQueries)
 	for q := range numQueries {
-		results[q] = make([]int, k)
-		distances[q] = make([]float32, k)
-		for i := range k {
-			results[q][i] = neighbors[q].SmallestK()[i].index
-			distances[q][i] = neighbors[q].SmallestK()[i].value
+		smallestK := neighbors[q].SmallestK()
+		results[q] = make([]int, len(smallestK))
+		distances[q] = make([]float32, len(smallestK))
+		for i, item := range smallestK {
+			results[q][i] = item.index
+			distances[q][i] = item.value
 		}
 	}
 
@@ -300,6 +513,100 @@ func (index *ProductQuantizationIndex[T]) NumVectors() int {
 	return index.state.NumVectors
 }
 
+func (index *ProductQuantizationIndex[T]) NumFeatures() int {
+	return index.state.NumFeatures
+}
+
+func (index *ProductQuantizationIndex[T]) Describe() string {
+	return describePQ(index.state.NumSubspaces, int(index.state.NumClusters))
+}
+
+func (index *ProductQuantizationIndex[T]) Info() IndexInfo {
+	codebooks := 0
+	for _, codebook := range index.state.Codebooks {
+		for _, centroid := range codebook {
+			codebooks += sizeOf[float32](len(centroid))
+		}
+	}
+	return IndexInfo{
+		IndexType:       IndexTypePQ,
+		CodeType1:       codeTypeName[T](),
+		CodeType2:       CodeTypeNameNone,
+		Spec:            index.Describe(),
+		NumFeatures:     index.state.NumFeatures,
+		NumVectors:      index.NumVectors(),
+		IsTrained:       index.state.IsTrained,
+		NumSubspaces:    index.state.NumSubspaces,
+		NumClusters:     int(index.state.NumClusters),
+		MaxIterations:   index.state.Config.MaxIterations,
+		Tolerance:       index.state.Config.Tolerance,
+		TrainSampleSize: index.state.Config.TrainSampleSize,
+		Memory: MemoryUsage{
+			Codes:     sizeOf[T](cap(index.state.Codes)),
+			Codebooks: codebooks,
+		}.withTotal(),
+	}
+}
+
+// emptyCopy returns a new index with a copy of the codebooks and no codes.
+func (index *ProductQuantizationIndex[T]) emptyCopy() (ANNIndex, error) {
+	state := *index.state
+	config := *index.state.Config
+	state.Config = &config
+	state.Codebooks = slices.Clone(index.state.Codebooks)
+	state.Codes = make([]T, 0)
+	state.NumVectors = 0
+
+	clusters := make([]*kmeans.KMeans, len(index.clusters))
+	for i, cluster := range index.clusters {
+		copied, err := copyCluster(cluster)
+		if err != nil {
+			return nil, err
+		}
+		clusters[i] = copied
+	}
+	return &ProductQuantizationIndex[T]{state: &state, clusters: clusters}, nil
+}
+
+// Merge appends the codes of other, which must use the same codebooks.
+func (index *ProductQuantizationIndex[T]) Merge(other ANNIndex) error {
+	err := index.checkMerge(other)
+	if err != nil {
+		return err
+	}
+	o := other.(*ProductQuantizationIndex[T])
+	numCodes := index.state.NumVectors * index.state.NumSubspaces
+	otherCodes := o.state.Codes[:o.state.NumVectors*o.state.NumSubspaces]
+	index.state.Codes = append(index.state.Codes[:numCodes], otherCodes...)
+	index.state.NumVectors += o.state.NumVectors
+	return nil
+}
+
+func (index *ProductQuantizationIndex[T]) checkMerge(other ANNIndex) error {
+	o, ok := other.(*ProductQuantizationIndex[T])
+	if !ok {
+		return newIndexError(OpMerge, IndexTypePQ, ErrIncompatibleIndex)
+	}
+	if o.state.NumFeatures != index.state.NumFeatures || o.state.NumSubspaces != index.state.NumSubspaces {
+		return newIndexError(OpMerge, IndexTypePQ, ErrIncompatibleIndex, "numSubspaces", index.state.NumSubspaces, "otherNumSubspaces", o.state.NumSubspaces)
+	}
+	if o.state.NumClusters != index.state.NumClusters {
+		return newIndexError(OpMerge, IndexTypePQ, ErrIncompatibleIndex, "numClusters", int(index.state.NumClusters), "otherNumClusters", int(o.state.NumClusters))
+	}
+	if o.state.NumVectors == 0 {
+		return nil
+	}
+	if !index.state.IsTrained {
+		return newIndexError(OpMerge, IndexTypePQ, ErrNotTrained)
+	}
+	for i := range index.state.NumSubspaces {
+		if !equalCentroids(index.state.Codebooks[i], o.state.Codebooks[i]) {
+			return newIndexError(OpMerge, IndexTypePQ, ErrIncompatibleIndex).withSubspace(i)
+		}
+	}
+	return nil
+}
+
 func (index *ProductQuantizationIndex[T]) Save(enc *gob.Encoder) error {
 	var t T
 	meta := MetaData{
@@ -4,6 +4,9 @@ type ProductQuantizationIndexOption func(*ProductQuantizationIndexConfig) error
 
 func WithPQMaxIterations(maxIterations int) ProductQuantizationIndexOption {
 	return func(config *ProductQuantizationIndexConfig) error {
+		if maxIterations <= 0 {
+			return ErrInvalidNumIterations
+		}
 		config.MaxIterations = maxIterations
 		return nil
 	}
@@ -11,7 +14,69 @@ func WithPQMaxIterations(maxIterations int) ProductQuantizationIndexOption {
 
 func WithPQTolerance(tol float32) ProductQuantizationIndexOption {
 	return func(config *ProductQuantizationIndexConfig) error {
+		if tol <= 0 {
+			return ErrInvalidTol
+		}
 		config.Tolerance = tol
 		return nil
 	}
 }
+
+// WithPQTrainSampleSize limits training to a random sample of sampleSize
+// vectors. Add still encodes every vector.
+func WithPQTrainSampleSize(sampleSize int) ProductQuantizationIndexOption {
+	return func(config *ProductQuantizationIndexConfig) error {
+		if sampleSize <= 0 {
+			return ErrInvalidSampleSize
+		}
+		config.TrainSampleSize = sampleSize
+		return nil
+	}
+}
+
+// WithPQSeed makes training reproducible. The seed drives sampling and the
+// initialization of every per-subspace kmeans, each of which then runs on a
+// single goroutine.
+func WithPQSeed(seed uint64) ProductQuantizationIndexOption {
+	return func(config *ProductQuantizationIndexConfig) error {
+		config.Seed = seed
+		config.IsSeeded = true
+		return nil
+	}
+}
+
+func WithPQInitMethod(method InitMethod) ProductQuantizationIndexOption {
+	return func(config *ProductQuantizationIndexConfig) error {
+		if method != InitKMeansPlusPlus && method != InitRandom {
+			return ErrInvalidInitMethod
+		}
+		config.InitMethod = method
+		return nil
+	}
+}
+
+// WithPQMiniBatch trains with mini-batch kmeans, sampling batchSize
+// vectors in each of numIterations iterations instead of running full-batch
+// Lloyd iterations over the whole training data.
+func WithPQMiniBatch(batchSize, numIterations int) ProductQuantizationIndexOption {
+	return func(config *ProductQuantizationIndexConfig) error {
+		if batchSize <= 0 {
+			return ErrInvalidBatchSize
+		}
+		if numIterations <= 0 {
+			return ErrInvalidNumIterations
+		}
+		config.MiniBatchSize = batchSize
+		config.MiniBatchIterations = numIterations
+		return nil
+	}
+}
+
+// WithPQTrainingCallback registers fn to receive the TrainingReport at the end
+// of every Train. The callback is not saved with the index.
+func WithPQTrainingCallback(fn TrainingCallback) ProductQuantizationIndexOption {
+	return func(config *ProductQuantizationIndexConfig) error {
+		config.TrainingCallback = fn
+		return nil
+	}
+}
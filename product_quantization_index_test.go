@@ -3,6 +3,9 @@ package vanadium_index
 import (
 	"bytes"
 	"encoding/gob"
+	"errors"
+	"math/rand/v2"
+	"reflect"
 	"testing"
 )
 
@@ -161,3 +164,293 @@ func TestProductQuantizationIndexSaveLoad(t *testing.T) {
 		}
 	}
 }
+
+func TestProductQuantizationIndexWithTrainSampleSize(t *testing.T) {
+	numFeatures := 4
+	index, err := newProductQuantizationIndex(numFeatures, 2, uint8(2), WithPQTrainSampleSize(2), WithPQSeed(1))
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+
+	data := []float32{
+		0.1, 0.2, 0.3, 0.4,
+		0.5, 0.6, 0.7, 0.8,
+		0.9, 1.0, 1.1, 1.2,
+		1.3, 1.4, 1.5, 1.6,
+	}
+
+	err = index.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+
+	err = index.Add(data)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	if index.NumVectors() != 4 {
+		t.Fatalf("NumVectors() = %d, expected 4", index.NumVectors())
+	}
+
+	_, err = newProductQuantizationIndex(numFeatures, 2, uint8(2), WithPQTrainSampleSize(0))
+	if !errors.Is(err, ErrInvalidSampleSize) {
+		t.Fatalf("expected ErrInvalidSampleSize, got %v", err)
+	}
+}
+
+func TestProductQuantizationIndexWithSeed(t *testing.T) {
+	numFeatures := 8
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 200*numFeatures)
+	for i := range data {
+		data[i] = rng.Float32()
+	}
+
+	codebooks := make([][][][]float32, 2)
+	for i := range codebooks {
+		index, err := newProductQuantizationIndex(numFeatures, 4, uint8(16), WithPQSeed(42))
+		if err != nil {
+			t.Fatalf("Failed to create index: %v", err)
+		}
+		err = index.Train(data)
+		if err != nil {
+			t.Fatalf("Failed to train index: %v", err)
+		}
+		codebooks[i] = index.state.Codebooks
+	}
+
+	for m := range codebooks[0] {
+		for c := range codebooks[0][m] {
+			for d := range codebooks[0][m][c] {
+				if codebooks[0][m][c][d] != codebooks[1][m][c][d] {
+					t.Fatalf("codebook mismatch: %v != %v", codebooks[0][m][c][d], codebooks[1][m][c][d])
+				}
+			}
+		}
+	}
+}
+
+func TestProductQuantizationIndexWithMiniBatch(t *testing.T) {
+	numFeatures := 4
+	index, err := newProductQuantizationIndex(numFeatures, 2, uint8(4),
+		WithPQInitMethod(InitRandom),
+		WithPQMiniBatch(2, 20),
+		WithPQSeed(1),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+
+	data := []float32{
+		0.1, 0.2, 0.3, 0.4,
+		0.5, 0.6, 0.7, 0.8,
+		0.9, 1.0, 1.1, 1.2,
+		1.3, 1.4, 1.5, 1.6,
+	}
+
+	err = index.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+
+	err = index.Add(data)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	results, _, err := index.Search(data, 1)
+	if err != nil {
+		t.Fatalf("Failed to search index: %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("len(results) = %d, expected 4", len(results))
+	}
+
+	_, err = newProductQuantizationIndex(numFeatures, 2, uint8(4), WithPQMiniBatch(0, 20))
+	if !errors.Is(err, ErrInvalidBatchSize) {
+		t.Fatalf("expected ErrInvalidBatchSize, got %v", err)
+	}
+
+	_, err = newProductQuantizationIndex(numFeatures, 2, uint8(4), WithPQInitMethod(InitMethod(-1)))
+	if !errors.Is(err, ErrInvalidInitMethod) {
+		t.Fatalf("expected ErrInvalidInitMethod, got %v", err)
+	}
+}
+
+func TestProductQuantizationIndexTrainingCallback(t *testing.T) {
+	numFeatures := 4
+	var report *TrainingReport
+	index, err := newProductQuantizationIndex(numFeatures, 2, uint8(4),
+		WithPQMaxIterations(10),
+		WithPQTrainingCallback(func(r *TrainingReport) {
+			report = r
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+
+	data := []float32{
+		0.1, 0.2, 0.3, 0.4,
+		0.5, 0.6, 0.7, 0.8,
+		0.9, 1.0, 1.1, 1.2,
+		1.3, 1.4, 1.5, 1.6,
+	}
+
+	err = index.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+
+	if report == nil {
+		t.Fatalf("training callback was not called")
+	}
+	if report.NumVectors != 4 {
+		t.Fatalf("report.NumVectors = %d, expected 4", report.NumVectors)
+	}
+	if len(report.Subspaces) != 2 {
+		t.Fatalf("len(report.Subspaces) = %d, expected 2", len(report.Subspaces))
+	}
+	for i, subspace := range report.Subspaces {
+		if subspace.Iterations <= 0 || subspace.Iterations > 10 {
+			t.Fatalf("report.Subspaces[%d].Iterations = %d, expected in (0, 10]", i, subspace.Iterations)
+		}
+		if subspace.EmptyClusters != 0 {
+			t.Fatalf("report.Subspaces[%d].EmptyClusters = %d, expected 0", i, subspace.EmptyClusters)
+		}
+	}
+	if report.MSE != 0 {
+		t.Fatalf("report.MSE = %f, expected 0", report.MSE)
+	}
+}
+
+func BenchmarkProductQuantizationIndexAddOneByOne(b *testing.B) {
+	numFeatures := 16
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 1024*numFeatures)
+	for i := range data {
+		data[i] = rng.Float32()
+	}
+
+	index, err := NewIndex(numFeatures, AsPQ(4, 16, WithPQMaxIterations(10), WithPQSeed(1)))
+	if err != nil {
+		b.Fatalf("Failed to create index: %v", err)
+	}
+	err = index.Train(data)
+	if err != nil {
+		b.Fatalf("Failed to train index: %v", err)
+	}
+
+	b.ResetTimer()
+	for i := range b.N {
+		n := i % 1024
+		err = index.Add(data[n*numFeatures : (n+1)*numFeatures])
+		if err != nil {
+			b.Fatalf("Failed to add data: %v", err)
+		}
+	}
+}
+
+func TestProductQuantizationIndexMerge(t *testing.T) {
+	numFeatures := 4
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 32*numFeatures)
+	for i := range data {
+		data[i] = rng.Float32()
+	}
+
+	model, err := NewIndex(numFeatures, AsPQ(2, 4, WithPQMaxIterations(10), WithPQSeed(1)))
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	err = model.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+	testMerge(t, model, data, numFeatures)
+
+	other, _ := NewIndex(numFeatures, AsPQ(2, 4, WithPQMaxIterations(10), WithPQSeed(2)))
+	err = other.Train(data[:16*numFeatures])
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+	err = other.Add(data)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	err = model.Merge(other)
+	if !errors.Is(err, ErrIncompatibleIndex) {
+		t.Fatalf("expected %v, got %v", ErrIncompatibleIndex, err)
+	}
+
+	flat, _ := NewIndex(numFeatures, AsFlat())
+	err = model.Merge(flat)
+	if !errors.Is(err, ErrIncompatibleIndex) {
+		t.Fatalf("expected %v, got %v", ErrIncompatibleIndex, err)
+	}
+}
+
+func TestProductQuantizationIndexEncodeDecode(t *testing.T) {
+	numFeatures := 4
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 64*numFeatures)
+	for i := range data {
+		data[i] = rng.Float32()
+	}
+	query := data[:4*numFeatures]
+
+	index, _ := newProductQuantizationIndex[uint8](numFeatures, 2, 8, WithPQMaxIterations(10), WithPQSeed(1))
+	_, err := index.Encode(data)
+	if !errors.Is(err, ErrNotTrained) {
+		t.Fatalf("expected %v, got %v", ErrNotTrained, err)
+	}
+	err = index.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+	err = index.Add(data)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	codes, err := index.Encode(data)
+	if err != nil {
+		t.Fatalf("Failed to encode data: %v", err)
+	}
+	if !reflect.DeepEqual(codes, index.state.Codes[:len(codes)]) {
+		t.Fatalf("Encode codes differ from the codes stored by Add")
+	}
+
+	results, distances, _ := index.Search(query, 5)
+	codeResults, codeDistances, err := index.SearchCodes(query, codes, 5)
+	if err != nil {
+		t.Fatalf("Failed to search codes: %v", err)
+	}
+	if !reflect.DeepEqual(results, codeResults) || !reflect.DeepEqual(distances, codeDistances) {
+		t.Fatalf("SearchCodes results differ from Search")
+	}
+
+	// The asymmetric distance is the distance to the reconstruction.
+	decoded, err := index.Decode(codes)
+	if err != nil {
+		t.Fatalf("Failed to decode codes: %v", err)
+	}
+	if len(decoded) != len(data) {
+		t.Fatalf("expected %d values, got %d", len(data), len(decoded))
+	}
+	r := results[0][0]
+	expected := squaredEuclideanDistance(query[:numFeatures], decoded[r*numFeatures:(r+1)*numFeatures])
+	if diff := expected - distances[0][0]; diff > 1e-5 || diff < -1e-5 {
+		t.Fatalf("expected distance %f, got %f", expected, distances[0][0])
+	}
+
+	_, err = index.Decode([]uint8{0, 8})
+	if !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("expected %v, got %v", ErrInvalidCode, err)
+	}
+	_, _, err = index.SearchCodes(query, []uint8{0, 1, 2}, 5)
+	if !errors.Is(err, ErrInvalidDataLength) {
+		t.Fatalf("expected %v, got %v", ErrInvalidDataLength, err)
+	}
+}
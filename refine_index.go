@@ -0,0 +1,356 @@
+package vanadium_index
+
+import (
+	"encoding/gob"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+)
+
+type RefineIndex struct {
+	state *RefineIndexState
+	index ANNIndex
+}
+
+type RefineIndexState struct {
+	NumFeatures int
+	KFactor     int
+	IsTrained   bool
+	Config      *RefineIndexConfig
+	Data        []float32
+	Codes       []uint8
+	Min         []float32
+	Scale       []float32
+}
+
+type RefineIndexConfig struct {
+	ScalarQuantized bool
+}
+
+func newRefineIndex(numFeatures int, index ANNIndex, kFactor int, opts ...RefineIndexOption) (*RefineIndex, error) {
+	if numFeatures <= 0 {
+		return nil, ErrInvalidNumFeatures
+	}
+	if kFactor <= 0 {
+		return nil, ErrInvalidKFactor
+	}
+
+	refine := &RefineIndex{
+		state: &RefineIndexState{
+			NumFeatures: numFeatures,
+			KFactor:     kFactor,
+			IsTrained:   false,
+			// Default values
+			Config: &RefineIndexConfig{
+				ScalarQuantized: false,
+			},
+			Data:  make([]float32, 0),
+			Codes: make([]uint8, 0),
+		},
+		index: index,
+	}
+	for _, opt := range opts {
+		err := opt(refine.state.Config)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return refine, nil
+}
+
+func loadRefineIndex(dec *gob.Decoder) (*RefineIndex, error) {
+	index := &RefineIndex{}
+	err := index.decode(dec)
+	if err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func (index *RefineIndex) Train(data []float32) error {
+	if len(data) == 0 {
+		return newIndexError(OpTrain, IndexTypeRefine, ErrEmptyData)
+	}
+
+	if len(data)%index.state.NumFeatures != 0 {
+		return newIndexError(OpTrain, IndexTypeRefine, ErrInvalidDataLength, "dataLength", len(data), "numFeatures", index.state.NumFeatures)
+	}
+
+	err := index.index.Train(data)
+	if err != nil {
+		return err
+	}
+
+	if index.state.Config.ScalarQuantized {
+		index.trainScalarQuantizer(data)
+	}
+
+	index.state.IsTrained = true
+	return nil
+}
+
+func (index *RefineIndex) Add(data []float32) error {
+	if len(data) == 0 {
+		return newIndexError(OpAdd, IndexTypeRefine, ErrEmptyData)
+	}
+
+	if len(data)%index.state.NumFeatures != 0 {
+		return newIndexError(OpAdd, IndexTypeRefine, ErrInvalidDataLength, "dataLength", len(data), "numFeatures", index.state.NumFeatures)
+	}
+
+	if index.state.Config.ScalarQuantized && !index.state.IsTrained {
+		return newIndexError(OpAdd, IndexTypeRefine, ErrNotTrained)
+	}
+
+	err := index.index.Add(data)
+	if err != nil {
+		return err
+	}
+
+	if index.state.Config.ScalarQuantized {
+		index.state.Codes = append(index.state.Codes, index.encodeScalarQuantizer(data)...)
+	} else {
+		index.state.Data = append(index.state.Data, data...)
+	}
+	return nil
+}
+
+func (index *RefineIndex) Search(query []float32, k int) ([][]int, [][]float32, error) {
+	if k <= 0 {
+		return nil, nil, newIndexError(OpSearch, IndexTypeRefine, ErrInvalidK, "k", k)
+	}
+
+	if len(query) == 0 {
+		return nil, nil, newIndexError(OpSearch, IndexTypeRefine, ErrEmptyData)
+	}
+
+	if len(query)%index.state.NumFeatures != 0 {
+		return nil, nil, newIndexError(OpSearch, IndexTypeRefine, ErrInvalidDataLength, "dataLength", len(query), "numFeatures", index.state.NumFeatures)
+	}
+
+	numQueries := len(query) / index.state.NumFeatures
+	numCandidates := min(k*index.state.KFactor, index.NumVectors())
+	if numCandidates == 0 {
+		return make([][]int, numQueries), make([][]float32, numQueries), nil
+	}
+
+	candidates, _, err := index.index.Search(query, numCandidates)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	type distanceItem struct {
+		index    int
+		distance float32
+	}
+
+	results := make([][]int, numQueries)
+	distances := make([][]float32, numQueries)
+	vector := make([]float32, index.state.NumFeatures)
+	for q := range numQueries {
+		subQuery := query[q*index.state.NumFeatures : (q+1)*index.state.NumFeatures]
+		items := make([]distanceItem, len(candidates[q]))
+		for i, n := range candidates[q] {
+			items[i] = distanceItem{
+				index:    n,
+				distance: index.squaredEuclideanDistance(subQuery, index.vector(n, vector)),
+			}
+		}
+		sort.Slice(items, func(i, j int) bool {
+			return items[i].distance < items[j].distance
+		})
+
+		numResults := min(k, len(items))
+		results[q] = make([]int, numResults)
+		distances[q] = make([]float32, numResults)
+		for i := range numResults {
+			results[q][i] = items[i].index
+			distances[q][i] = items[i].distance
+		}
+	}
+
+	return results, distances, nil
+}
+
+func (index *RefineIndex) NumVectors() int {
+	return index.index.NumVectors()
+}
+
+func (index *RefineIndex) NumFeatures() int {
+	return index.state.NumFeatures
+}
+
+func (index *RefineIndex) Describe() string {
+	desc := fmt.Sprintf("%s,Refine%d", index.index.Describe(), index.state.KFactor)
+	if index.state.Config.ScalarQuantized {
+		desc += "(SQ8)"
+	}
+	return desc
+}
+
+func (index *RefineIndex) Info() IndexInfo {
+	sub := index.index.Info()
+	memory := MemoryUsage{
+		Data:      sizeOf[float32](cap(index.state.Data)),
+		Codes:     sizeOf[uint8](cap(index.state.Codes)),
+		Codebooks: sizeOf[float32](len(index.state.Min) + len(index.state.Scale)),
+	}.withTotal()
+	return IndexInfo{
+		IndexType:   IndexTypeRefine,
+		CodeType1:   CodeTypeNameNone,
+		CodeType2:   CodeTypeNameNone,
+		Spec:        index.Describe(),
+		NumFeatures: index.state.NumFeatures,
+		NumVectors:  index.NumVectors(),
+		IsTrained:   sub.IsTrained && (index.state.IsTrained || !index.state.Config.ScalarQuantized),
+		KFactor:     index.state.KFactor,
+		Memory:      memory.add(sub.Memory),
+		Sub:         &sub,
+	}
+}
+
+// emptyCopy returns a new index with a copy of the base model and of the
+// scalar quantizer, and no vectors.
+func (index *RefineIndex) emptyCopy() (ANNIndex, error) {
+	state := *index.state
+	config := *index.state.Config
+	state.Config = &config
+	state.Data = make([]float32, 0)
+	state.Codes = make([]uint8, 0)
+	state.Min = slices.Clone(index.state.Min)
+	state.Scale = slices.Clone(index.state.Scale)
+
+	base, err := index.index.emptyCopy()
+	if err != nil {
+		return nil, err
+	}
+	return &RefineIndex{state: &state, index: base}, nil
+}
+
+// Merge merges the base index of other into the base index of index and
+// appends the vectors kept for reranking.
+func (index *RefineIndex) Merge(other ANNIndex) error {
+	err := index.checkMerge(other)
+	if err != nil {
+		return err
+	}
+	o := other.(*RefineIndex)
+	err = index.index.Merge(o.index)
+	if err != nil {
+		return err
+	}
+	index.state.Data = append(index.state.Data, o.state.Data...)
+	index.state.Codes = append(index.state.Codes, o.state.Codes...)
+	return nil
+}
+
+func (index *RefineIndex) checkMerge(other ANNIndex) error {
+	o, ok := other.(*RefineIndex)
+	if !ok || o.state.Config.ScalarQuantized != index.state.Config.ScalarQuantized {
+		return newIndexError(OpMerge, IndexTypeRefine, ErrIncompatibleIndex)
+	}
+	if o.state.NumFeatures != index.state.NumFeatures {
+		return newIndexError(OpMerge, IndexTypeRefine, ErrIncompatibleIndex, "numFeatures", index.state.NumFeatures, "otherNumFeatures", o.state.NumFeatures)
+	}
+	if index.state.Config.ScalarQuantized && o.NumVectors() > 0 &&
+		(!slices.Equal(index.state.Min, o.state.Min) || !slices.Equal(index.state.Scale, o.state.Scale)) {
+		return newIndexError(OpMerge, IndexTypeRefine, ErrIncompatibleIndex)
+	}
+	return index.index.checkMerge(o.index)
+}
+
+func (index *RefineIndex) Save(enc *gob.Encoder) error {
+	meta := MetaData{
+		IndexType: IndexTypeRefine,
+		CodeType1: CodeTypeNameNone,
+		CodeType2: CodeTypeNameNone,
+	}
+	err := enc.Encode(meta)
+	if err != nil {
+		return err
+	}
+	return index.encode(enc)
+}
+
+func (index *RefineIndex) encode(enc *gob.Encoder) error {
+	err := enc.Encode(index.state)
+	if err != nil {
+		return err
+	}
+	return index.index.Save(enc)
+}
+
+func (index *RefineIndex) decode(dec *gob.Decoder) error {
+	index.state = &RefineIndexState{
+		Config: &RefineIndexConfig{},
+	}
+	err := dec.Decode(index.state)
+	if err != nil {
+		return err
+	}
+
+	subIndex, err := LoadIndex(dec)
+	if err != nil {
+		return err
+	}
+	index.index = subIndex
+	return nil
+}
+
+func (index *RefineIndex) vector(n int, buf []float32) []float32 {
+	if !index.state.Config.ScalarQuantized {
+		return index.state.Data[n*index.state.NumFeatures : (n+1)*index.state.NumFeatures]
+	}
+	codes := index.state.Codes[n*index.state.NumFeatures : (n+1)*index.state.NumFeatures]
+	for d, code := range codes {
+		buf[d] = index.state.Min[d] + float32(code)*index.state.Scale[d]
+	}
+	return buf
+}
+
+func (index *RefineIndex) trainScalarQuantizer(data []float32) {
+	numFeatures := index.state.NumFeatures
+	minValues := make([]float32, numFeatures)
+	maxValues := make([]float32, numFeatures)
+	for d := range numFeatures {
+		minValues[d] = float32(math.Inf(1))
+		maxValues[d] = float32(math.Inf(-1))
+	}
+	for n := range len(data) / numFeatures {
+		for d := range numFeatures {
+			value := data[n*numFeatures+d]
+			minValues[d] = min(minValues[d], value)
+			maxValues[d] = max(maxValues[d], value)
+		}
+	}
+
+	scale := make([]float32, numFeatures)
+	for d := range numFeatures {
+		scale[d] = (maxValues[d] - minValues[d]) / math.MaxUint8
+	}
+	index.state.Min = minValues
+	index.state.Scale = scale
+}
+
+func (index *RefineIndex) encodeScalarQuantizer(data []float32) []uint8 {
+	codes := make([]uint8, len(data))
+	for i, value := range data {
+		d := i % index.state.NumFeatures
+		if index.state.Scale[d] == 0 {
+			continue
+		}
+		code := math.Round(float64((value - index.state.Min[d]) / index.state.Scale[d]))
+		codes[i] = uint8(max(0, min(math.MaxUint8, code)))
+	}
+	return codes
+}
+
+func (index *RefineIndex) squaredEuclideanDistance(x, y []float32) float32 {
+	distance := float32(0)
+	for i := range x {
+		diff := x[i] - y[i]
+		distance += diff * diff
+	}
+	return distance
+}
@@ -0,0 +1,10 @@
+package vanadium_index
+
+type RefineIndexOption func(*RefineIndexConfig) error
+
+func WithRefineScalarQuantizer() RefineIndexOption {
+	return func(config *RefineIndexConfig) error {
+		config.ScalarQuantized = true
+		return nil
+	}
+}
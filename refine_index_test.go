@@ -0,0 +1,197 @@
+package vanadium_index
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"testing"
+)
+
+func TestRefineIndex(t *testing.T) {
+	numFeatures := 4
+	data := []float32{
+		0.1, 0.2, 0.3, 0.4,
+		0.5, 0.6, 0.7, 0.8,
+		0.9, 1.0, 1.1, 1.2,
+		1.3, 1.4, 1.5, 1.6,
+	}
+
+	index, err := NewIndex(numFeatures, AsRefine(AsPQ(1, 2, WithPQMaxIterations(10)), 4))
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+
+	err = index.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+
+	err = index.Add(data)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	results, distances, err := index.Search(data, 1)
+	if err != nil {
+		t.Fatalf("Failed to search index: %v", err)
+	}
+
+	for i, result := range results {
+		if result[0] != i {
+			t.Fatalf("result[%d] = %d, expected %d", i, result[0], i)
+		}
+	}
+
+	for i, distance := range distances {
+		if distance[0] != 0 {
+			t.Fatalf("distance[%d] = %f, expected 0", i, distance[0])
+		}
+	}
+}
+
+func TestRefineIndexWithScalarQuantizer(t *testing.T) {
+	numFeatures := 4
+	data := []float32{
+		0.1, 0.2, 0.3, 0.4,
+		0.5, 0.6, 0.7, 0.8,
+		0.9, 1.0, 1.1, 1.2,
+		1.3, 1.4, 1.5, 1.6,
+	}
+
+	index, err := NewIndex(numFeatures, AsRefine(AsPQ(1, 2, WithPQMaxIterations(10)), 4, WithRefineScalarQuantizer()))
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+
+	err = index.Add(data)
+	if !errors.Is(err, ErrNotTrained) {
+		t.Fatalf("expected ErrNotTrained, got %v", err)
+	}
+
+	err = index.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+
+	err = index.Add(data)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	results, distances, err := index.Search(data, 2)
+	if err != nil {
+		t.Fatalf("Failed to search index: %v", err)
+	}
+
+	for i, result := range results {
+		if len(result) != 2 {
+			t.Fatalf("len(result[%d]) = %d, expected 2", i, len(result))
+		}
+		if result[0] != i {
+			t.Fatalf("result[%d] = %d, expected %d", i, result[0], i)
+		}
+		if distances[i][0] > distances[i][1] {
+			t.Fatalf("distances[%d] are not sorted: %v", i, distances[i])
+		}
+	}
+}
+
+func TestRefineIndexSaveLoad(t *testing.T) {
+	numFeatures := 4
+	data := []float32{
+		0.1, 0.2, 0.3, 0.4,
+		0.5, 0.6, 0.7, 0.8,
+		0.9, 1.0, 1.1, 1.2,
+		1.3, 1.4, 1.5, 1.6,
+	}
+
+	annIndex, err := NewIndex(numFeatures, AsRefine(AsPQ(1, 2, WithPQMaxIterations(10)), 4, WithRefineScalarQuantizer()))
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	index := annIndex.(*RefineIndex)
+
+	err = index.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+
+	err = index.Add(data)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	err = index.Save(enc)
+	if err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+
+	dec := gob.NewDecoder(&buf)
+	annIndex, err = LoadIndex(dec)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	index2, ok := annIndex.(*RefineIndex)
+	if !ok {
+		t.Fatalf("loaded index is not a RefineIndex")
+	}
+
+	if index2.state.KFactor != index.state.KFactor {
+		t.Fatalf("kFactor mismatch: %d != %d", index2.state.KFactor, index.state.KFactor)
+	}
+
+	if index2.state.Config.ScalarQuantized != index.state.Config.ScalarQuantized {
+		t.Fatalf("scalarQuantized mismatch: %t != %t", index2.state.Config.ScalarQuantized, index.state.Config.ScalarQuantized)
+	}
+
+	if index2.NumVectors() != index.NumVectors() {
+		t.Fatalf("numVectors mismatch: %d != %d", index2.NumVectors(), index.NumVectors())
+	}
+
+	if _, ok := index2.index.(*ProductQuantizationIndex[uint8]); !ok {
+		t.Fatalf("loaded base index is not a ProductQuantizationIndex")
+	}
+
+	for i := range index.state.Codes {
+		if index.state.Codes[i] != index2.state.Codes[i] {
+			t.Fatalf("code mismatch: %v != %v", index.state.Codes[i], index2.state.Codes[i])
+		}
+	}
+
+	results1, _, err := index.Search(data, 1)
+	if err != nil {
+		t.Fatalf("Failed to search index: %v", err)
+	}
+	results2, _, err := index2.Search(data, 1)
+	if err != nil {
+		t.Fatalf("Failed to search loaded index: %v", err)
+	}
+	for i := range results1 {
+		if results1[i][0] != results2[i][0] {
+			t.Fatalf("result mismatch: %d != %d", results1[i][0], results2[i][0])
+		}
+	}
+}
+
+func TestRefineIndexMerge(t *testing.T) {
+	numFeatures := 4
+	data := []float32{
+		0.1, 0.2, 0.3, 0.4,
+		0.5, 0.6, 0.7, 0.8,
+		0.9, 1.0, 1.1, 1.2,
+		1.3, 1.4, 1.5, 1.6,
+	}
+
+	model, err := NewIndex(numFeatures, AsRefine(AsPQ(2, 2, WithPQMaxIterations(10), WithPQSeed(1)), 2, WithRefineScalarQuantizer()))
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	err = model.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+	testMerge(t, model, data, numFeatures)
+}
@@ -0,0 +1,31 @@
+package vanadium_index
+
+import "compress/gzip"
+
+type Compression int
+
+const (
+	CompressionNone Compression = iota
+	CompressionGzip
+)
+
+type SaveOption func(*SaveConfig) error
+
+type SaveConfig struct {
+	Compression      Compression
+	CompressionLevel int
+}
+
+// WithGzip compresses the saved index with gzip at the given level, from
+// gzip.HuffmanOnly to gzip.BestCompression. Loading detects compression, so
+// no option is needed to read the file back.
+func WithGzip(level int) SaveOption {
+	return func(config *SaveConfig) error {
+		if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+			return ErrInvalidCompressionLevel
+		}
+		config.Compression = CompressionGzip
+		config.CompressionLevel = level
+		return nil
+	}
+}
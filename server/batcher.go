@@ -0,0 +1,113 @@
+package server
+
+import (
+	"time"
+)
+
+// searchFunc searches the queries of several requests in one Search call and
+// returns a response for each request.
+type searchFunc func(queries [][]float32, k int) []searchResponse
+
+type searchRequest struct {
+	query      []float32
+	k          int
+	numQueries int // sizes the batch; searchFunc splits the results
+	done       chan searchResponse
+}
+
+type searchResponse struct {
+	results   [][]int
+	distances [][]float32
+	err       error
+}
+
+// batcher combines concurrent search requests into one Search call per k,
+// waiting at most maxDelay for up to maxBatchSize queries.
+type batcher struct {
+	requests     chan *searchRequest
+	stop         chan struct{}
+	stopped      chan struct{}
+	maxBatchSize int
+	maxDelay     time.Duration
+	search       searchFunc
+}
+
+func newBatcher(maxBatchSize int, maxDelay time.Duration, search searchFunc) *batcher {
+	b := &batcher{
+		requests:     make(chan *searchRequest),
+		stop:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+		maxBatchSize: maxBatchSize,
+		maxDelay:     maxDelay,
+		search:       search,
+	}
+	go b.run()
+	return b
+}
+
+func (b *batcher) Search(query []float32, k, numQueries int) ([][]int, [][]float32, error) {
+	req := &searchRequest{
+		query:      query,
+		k:          k,
+		numQueries: numQueries,
+		done:       make(chan searchResponse, 1),
+	}
+	select {
+	case b.requests <- req:
+	case <-b.stopped:
+		res := b.search([][]float32{query}, k)[0]
+		return res.results, res.distances, res.err
+	}
+	res := <-req.done
+	return res.results, res.distances, res.err
+}
+
+func (b *batcher) Close() {
+	close(b.stop)
+	<-b.stopped
+}
+
+func (b *batcher) run() {
+	defer close(b.stopped)
+	for {
+		select {
+		case req := <-b.requests:
+			batch := []*searchRequest{req}
+			numQueries := req.numQueries
+			timer := time.NewTimer(b.maxDelay)
+		collect:
+			for numQueries < b.maxBatchSize {
+				select {
+				case req := <-b.requests:
+					batch = append(batch, req)
+					numQueries += req.numQueries
+				case <-timer.C:
+					break collect
+				case <-b.stop:
+					break collect
+				}
+			}
+			timer.Stop()
+			b.flush(batch)
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+func (b *batcher) flush(batch []*searchRequest) {
+	groups := map[int][]*searchRequest{}
+	for _, req := range batch {
+		groups[req.k] = append(groups[req.k], req)
+	}
+
+	for k, group := range groups {
+		queries := make([][]float32, len(group))
+		for i, req := range group {
+			queries[i] = req.query
+		}
+		for i, res := range b.search(queries, k) {
+			group[i].done <- res
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Command vanadium-server serves saved vanadium indexes over HTTP/JSON and
+// gRPC.
+//
+//	vanadium-server -addr :8080 -grpc-addr :9090 -index products=products.index -index users=users.index
+//
+// SIGHUP reloads every index from its file. SIGINT and SIGTERM stop the
+// server after in-flight requests finish.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/monochromegane/vanadium-index/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type indexFlags map[string]string
+
+func (f indexFlags) String() string {
+	return fmt.Sprint(map[string]string(f))
+}
+
+func (f indexFlags) Set(value string) error {
+	name, path, ok := strings.Cut(value, "=")
+	if !ok || name == "" || path == "" {
+		return fmt.Errorf("index must be given as name=path: %q", value)
+	}
+	f[name] = path
+	return nil
+}
+
+func main() {
+	indexes := indexFlags{}
+	addr := flag.String("addr", ":8080", "address to listen on for HTTP/JSON")
+	grpcAddr := flag.String("grpc-addr", ":9090", "address to listen on for gRPC, empty to disable gRPC")
+	maxBatchSize := flag.Int("batch-size", 64, "maximum number of queries combined into one search, 0 to disable batching")
+	maxDelay := flag.Duration("batch-delay", time.Millisecond, "maximum time a query waits for a batch")
+	maxRequestBytes := flag.Int64("max-request-bytes", 32<<20, "maximum size of an HTTP request body")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 30*time.Second, "time to wait for in-flight requests on shutdown")
+	flag.Var(indexes, "index", "index to serve as name=path, may be repeated")
+	flag.Parse()
+
+	if len(indexes) == 0 {
+		log.Fatal("at least one -index is required")
+	}
+
+	opts := []server.Option{server.WithMaxRequestBytes(*maxRequestBytes)}
+	if *maxBatchSize > 0 {
+		opts = append(opts, server.WithBatching(*maxBatchSize, *maxDelay))
+	}
+	srv, err := server.New(opts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer srv.Close()
+
+	for name, path := range indexes {
+		err = srv.LoadIndex(name, path)
+		if err != nil {
+			log.Fatalf("failed to load %s from %s: %v", name, path, err)
+		}
+		log.Printf("loaded %s from %s", name, path)
+	}
+
+	httpServer := &http.Server{
+		Addr:    *addr,
+		Handler: srv.Handler(),
+	}
+
+	var grpcServer *grpc.Server
+	if *grpcAddr != "" {
+		listener, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		grpcServer = grpc.NewServer()
+		srv.RegisterGRPC(grpcServer)
+		healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+		go func() {
+			log.Printf("listening for gRPC on %s", *grpcAddr)
+			err := grpcServer.Serve(listener)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			err := srv.ReloadAll()
+			if err != nil {
+				log.Printf("reload failed: %v", err)
+				continue
+			}
+			log.Print("reloaded indexes")
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+		defer cancel()
+		err := httpServer.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Printf("shutdown failed: %v", err)
+		}
+		if grpcServer != nil {
+			stopped := make(chan struct{})
+			go func() {
+				grpcServer.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+			case <-shutdownCtx.Done():
+				grpcServer.Stop()
+			}
+		}
+	}()
+
+	log.Printf("listening on %s", *addr)
+	err = httpServer.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	<-shutdown
+}
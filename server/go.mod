@@ -0,0 +1,20 @@
+module github.com/monochromegane/vanadium-index/server
+
+go 1.24.2
+
+require (
+	github.com/monochromegane/vanadium-index v0.0.3
+	google.golang.org/grpc v1.72.2
+	google.golang.org/protobuf v1.36.6
+)
+
+require (
+	github.com/monochromegane/kmeans v0.0.3 // indirect
+	golang.org/x/net v0.35.0 // indirect
+	golang.org/x/sync v0.13.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250218202821-56aae31c358a // indirect
+)
+
+replace github.com/monochromegane/vanadium-index => ../
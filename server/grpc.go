@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/monochromegane/vanadium-index/server/vanadiumpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type grpcService struct {
+	vanadiumpb.UnimplementedVanadiumServer
+	server *Server
+}
+
+// RegisterGRPC registers the Vanadium gRPC service, which serves the same
+// indexes as Handler, on registrar.
+func (s *Server) RegisterGRPC(registrar grpc.ServiceRegistrar) {
+	vanadiumpb.RegisterVanadiumServer(registrar, &grpcService{server: s})
+}
+
+func (g *grpcService) ListIndexes(ctx context.Context, req *vanadiumpb.ListIndexesRequest) (*vanadiumpb.ListIndexesResponse, error) {
+	return &vanadiumpb.ListIndexesResponse{Indexes: g.server.Names()}, nil
+}
+
+func (g *grpcService) GetInfo(ctx context.Context, req *vanadiumpb.GetInfoRequest) (*vanadiumpb.GetInfoResponse, error) {
+	info, err := g.server.Info(req.Index)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &vanadiumpb.GetInfoResponse{
+		IndexType:   string(info.IndexType),
+		Spec:        info.Spec,
+		NumFeatures: int64(info.NumFeatures),
+		NumVectors:  int64(info.NumVectors),
+		IsTrained:   info.IsTrained,
+	}, nil
+}
+
+func (g *grpcService) Add(ctx context.Context, req *vanadiumpb.AddRequest) (*vanadiumpb.AddResponse, error) {
+	data, err := g.server.flatten(req.Index, vectorValues(req.Vectors))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	err = g.server.Add(req.Index, data)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	numVectors, err := g.server.NumVectors(req.Index)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &vanadiumpb.AddResponse{NumVectors: int64(numVectors)}, nil
+}
+
+func (g *grpcService) Search(ctx context.Context, req *vanadiumpb.SearchRequest) (*vanadiumpb.SearchResponse, error) {
+	query, err := g.server.flatten(req.Index, vectorValues(req.Vectors))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	results, distances, err := g.server.Search(req.Index, query, int(req.K))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return neighborsResponse(results, distances), nil
+}
+
+func (g *grpcService) RangeSearch(ctx context.Context, req *vanadiumpb.RangeSearchRequest) (*vanadiumpb.SearchResponse, error) {
+	query, err := g.server.flatten(req.Index, vectorValues(req.Vectors))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	results, distances, err := g.server.RangeSearch(req.Index, query, req.Radius)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return neighborsResponse(results, distances), nil
+}
+
+func (g *grpcService) Save(ctx context.Context, req *vanadiumpb.SaveRequest) (*vanadiumpb.SaveResponse, error) {
+	err := g.server.Save(req.Index)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &vanadiumpb.SaveResponse{}, nil
+}
+
+func (g *grpcService) Reload(ctx context.Context, req *vanadiumpb.ReloadRequest) (*vanadiumpb.ReloadResponse, error) {
+	err := g.server.Reload(req.Index)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &vanadiumpb.ReloadResponse{}, nil
+}
+
+func vectorValues(vectors []*vanadiumpb.Vector) [][]float32 {
+	values := make([][]float32, len(vectors))
+	for i, vector := range vectors {
+		values[i] = vector.GetValues()
+	}
+	return values
+}
+
+func neighborsResponse(results [][]int, distances [][]float32) *vanadiumpb.SearchResponse {
+	res := &vanadiumpb.SearchResponse{Results: make([]*vanadiumpb.Neighbors, len(results))}
+	for q := range results {
+		ids := make([]int64, len(results[q]))
+		for i, id := range results[q] {
+			ids[i] = int64(id)
+		}
+		res.Results[q] = &vanadiumpb.Neighbors{Ids: ids, Distances: distances[q]}
+	}
+	return res
+}
+
+// grpcError maps err to a status with the code matching the HTTP status
+// returned by writeError.
+func grpcError(err error) error {
+	code := codes.Internal
+	if errors.Is(err, ErrUnknownIndex) {
+		code = codes.NotFound
+	}
+	for _, badRequest := range badRequestErrors {
+		if errors.Is(err, badRequest) {
+			code = codes.InvalidArgument
+		}
+	}
+	return status.Error(code, err.Error())
+}
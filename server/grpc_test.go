@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/monochromegane/vanadium-index/server/vanadiumpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestServerGRPC(t *testing.T) {
+	path := newFlatIndexFile(t, []float32{0, 0, 1, 1, 2, 2})
+	srv, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+	err = srv.LoadIndex("flat", path)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	srv.RegisterGRPC(grpcServer)
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	client := vanadiumpb.NewVanadiumClient(conn)
+	ctx := context.Background()
+
+	added, err := client.Add(ctx, &vanadiumpb.AddRequest{
+		Index:   "flat",
+		Vectors: []*vanadiumpb.Vector{{Values: []float32{3, 3}}},
+	})
+	if err != nil || added.NumVectors != 4 {
+		t.Fatalf("expected 4 vectors, got %v %v", added, err)
+	}
+
+	found, err := client.Search(ctx, &vanadiumpb.SearchRequest{
+		Index:   "flat",
+		Vectors: []*vanadiumpb.Vector{{Values: []float32{3, 3}}, {Values: []float32{0, 0}}},
+		K:       2,
+	})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	results := [][]int64{}
+	for _, neighbors := range found.Results {
+		results = append(results, neighbors.Ids)
+	}
+	if !reflect.DeepEqual(results, [][]int64{{3, 2}, {0, 1}}) {
+		t.Fatalf("unexpected results %v", results)
+	}
+
+	found, err = client.RangeSearch(ctx, &vanadiumpb.RangeSearchRequest{
+		Index:   "flat",
+		Vectors: []*vanadiumpb.Vector{{Values: []float32{0, 0}}},
+		Radius:  2,
+	})
+	if err != nil || !reflect.DeepEqual(found.Results[0].Ids, []int64{0, 1}) {
+		t.Fatalf("unexpected range results %v %v", found, err)
+	}
+
+	info, err := client.GetInfo(ctx, &vanadiumpb.GetInfoRequest{Index: "flat"})
+	if err != nil || info.NumFeatures != 2 || info.NumVectors != 4 || info.Spec != "Flat" {
+		t.Fatalf("unexpected info %v %v", info, err)
+	}
+
+	_, err = client.Search(ctx, &vanadiumpb.SearchRequest{
+		Index:   "flat",
+		Vectors: []*vanadiumpb.Vector{{Values: []float32{1, 2, 3}}, {Values: []float32{4}}},
+		K:       1,
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected %v, got %v", codes.InvalidArgument, err)
+	}
+	_, err = client.Search(ctx, &vanadiumpb.SearchRequest{
+		Index:   "missing",
+		Vectors: []*vanadiumpb.Vector{{Values: []float32{1, 2}}},
+		K:       1,
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected %v, got %v", codes.NotFound, err)
+	}
+
+	_, err = client.Save(ctx, &vanadiumpb.SaveRequest{Index: "flat"})
+	if err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	_, err = client.Reload(ctx, &vanadiumpb.ReloadRequest{Index: "flat"})
+	if err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	numVectors, _ := srv.NumVectors("flat")
+	if numVectors != 4 {
+		t.Fatalf("expected saved index with 4 vectors, got %d", numVectors)
+	}
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	vanadium "github.com/monochromegane/vanadium-index"
+)
+
+type vectorsRequest struct {
+	Vectors [][]float32 `json:"vectors"`
+	K       int         `json:"k,omitempty"`
+	Radius  float32     `json:"radius,omitempty"`
+}
+
+type searchResponseBody struct {
+	Results   [][]int     `json:"results"`
+	Distances [][]float32 `json:"distances"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Handler returns the HTTP handler serving the routes listed in the package
+// documentation.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("GET /indexes", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string][]string{"indexes": s.Names()})
+	})
+	mux.HandleFunc("GET /indexes/{name}", func(w http.ResponseWriter, r *http.Request) {
+		info, err := s.Info(r.PathValue("name"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, info)
+	})
+	mux.HandleFunc("POST /indexes/{name}/add", func(w http.ResponseWriter, r *http.Request) {
+		var req vectorsRequest
+		if !s.readJSON(w, r, &req) {
+			return
+		}
+		data, err := s.flatten(r.PathValue("name"), req.Vectors)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		err = s.Add(r.PathValue("name"), data)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		numVectors, err := s.NumVectors(r.PathValue("name"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"num_vectors": numVectors})
+	})
+	mux.HandleFunc("POST /indexes/{name}/search", func(w http.ResponseWriter, r *http.Request) {
+		var req vectorsRequest
+		if !s.readJSON(w, r, &req) {
+			return
+		}
+		query, err := s.flatten(r.PathValue("name"), req.Vectors)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		results, distances, err := s.Search(r.PathValue("name"), query, req.K)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, searchResponseBody{results, distances})
+	})
+	mux.HandleFunc("POST /indexes/{name}/range_search", func(w http.ResponseWriter, r *http.Request) {
+		var req vectorsRequest
+		if !s.readJSON(w, r, &req) {
+			return
+		}
+		query, err := s.flatten(r.PathValue("name"), req.Vectors)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		results, distances, err := s.RangeSearch(r.PathValue("name"), query, req.Radius)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, searchResponseBody{results, distances})
+	})
+	mux.HandleFunc("POST /indexes/{name}/save", func(w http.ResponseWriter, r *http.Request) {
+		err := s.Save(r.PathValue("name"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("POST /indexes/{name}/reload", func(w http.ResponseWriter, r *http.Request) {
+		err := s.Reload(r.PathValue("name"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	return mux
+}
+
+// flatten concatenates vectors, each of which must have as many values as the
+// index served as name has features.
+func (s *Server) flatten(name string, vectors [][]float32) ([]float32, error) {
+	numFeatures, err := s.NumFeatures(name)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]float32, 0, len(vectors)*numFeatures)
+	for i, vector := range vectors {
+		if len(vector) != numFeatures {
+			return nil, fmt.Errorf("%w: vector %d has %d values for %d features", vanadium.ErrInvalidDataLength, i, len(vector), numFeatures)
+		}
+		data = append(data, vector...)
+	}
+	return data, nil
+}
+
+func (s *Server) readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	body := http.MaxBytesReader(w, r.Body, s.config.MaxRequestBytes)
+	err := json.NewDecoder(body).Decode(v)
+	if err != nil {
+		status := http.StatusBadRequest
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeJSON(w, status, errorResponse{err.Error()})
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+var badRequestErrors = []error{
+	vanadium.ErrInvalidDataLength,
+	vanadium.ErrEmptyData,
+	vanadium.ErrInvalidK,
+	vanadium.ErrNotTrained,
+	ErrNoIndexPath,
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, ErrUnknownIndex) {
+		status = http.StatusNotFound
+	}
+	for _, badRequest := range badRequestErrors {
+		if errors.Is(err, badRequest) {
+			status = http.StatusBadRequest
+		}
+	}
+	writeJSON(w, status, errorResponse{err.Error()})
+}
@@ -0,0 +1,382 @@
+// Package server exposes vanadium indexes over HTTP/JSON and gRPC.
+//
+// Each index is registered under a name and served at /indexes/{name}:
+//
+//	GET  /healthz                        health check
+//	GET  /indexes                        names of the loaded indexes
+//	GET  /indexes/{name}                 index info
+//	POST /indexes/{name}/add             {"vectors": [[...], ...]}
+//	POST /indexes/{name}/search          {"vectors": [[...], ...], "k": 10}
+//	POST /indexes/{name}/range_search    {"vectors": [[...], ...], "radius": 0.5}
+//	POST /indexes/{name}/save            write the index to its file
+//	POST /indexes/{name}/reload          read the index from its file
+//
+// Save and reload use the file the index was loaded from; clients cannot name
+// other files. Request bodies are limited to Config.MaxRequestBytes.
+//
+// RegisterGRPC serves the same operations as the Vanadium gRPC service
+// defined in vanadiumpb/vanadium.proto.
+//
+// The indexes have no native range search, so range_search searches every
+// vector and keeps those within radius. Its results are exact for flat
+// indexes and approximate otherwise.
+package server
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	vanadium "github.com/monochromegane/vanadium-index"
+)
+
+var ErrUnknownIndex = fmt.Errorf("unknown index")
+
+var ErrNoIndexPath = fmt.Errorf("index has no file path")
+
+var ErrInvalidMaxRequestBytes = fmt.Errorf("max request bytes must be positive")
+
+type Option func(*Config) error
+
+type Config struct {
+	MaxBatchSize    int
+	MaxDelay        time.Duration
+	MaxRequestBytes int64
+}
+
+// defaultMaxRequestBytes is the default limit on the size of an HTTP request
+// body.
+const defaultMaxRequestBytes = 32 << 20
+
+// WithBatching combines concurrent searches on the same index into one
+// Search call of up to maxBatchSize queries, waiting at most maxDelay for
+// queries to arrive.
+func WithBatching(maxBatchSize int, maxDelay time.Duration) Option {
+	return func(config *Config) error {
+		if maxBatchSize <= 0 || maxDelay <= 0 {
+			return vanadium.ErrInvalidBatchSize
+		}
+		config.MaxBatchSize = maxBatchSize
+		config.MaxDelay = maxDelay
+		return nil
+	}
+}
+
+// WithMaxRequestBytes limits HTTP request bodies to n bytes. Larger requests
+// are rejected with 413 Request Entity Too Large.
+func WithMaxRequestBytes(n int64) Option {
+	return func(config *Config) error {
+		if n <= 0 {
+			return ErrInvalidMaxRequestBytes
+		}
+		config.MaxRequestBytes = n
+		return nil
+	}
+}
+
+type Server struct {
+	config  *Config
+	mu      sync.RWMutex
+	entries map[string]*entry
+}
+
+type entry struct {
+	mu      sync.RWMutex
+	index   vanadium.ANNIndex
+	path    string
+	batcher *batcher
+}
+
+func New(opts ...Option) (*Server, error) {
+	config := &Config{MaxRequestBytes: defaultMaxRequestBytes}
+	for _, opt := range opts {
+		err := opt(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Server{
+		config:  config,
+		entries: map[string]*entry{},
+	}, nil
+}
+
+// LoadIndex loads the index saved at path and serves it as name, replacing
+// any index already served under that name.
+func (s *Server) LoadIndex(name, path string) error {
+	index, err := vanadium.LoadFile(path)
+	if err != nil {
+		return err
+	}
+	s.SetIndex(name, index, path)
+	return nil
+}
+
+// SetIndex serves index as name. path is where Save and Reload write and
+// read the index, and may be empty.
+func (s *Server) SetIndex(name string, index vanadium.ANNIndex, path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[name]; ok {
+		e.swap(index, path)
+		return
+	}
+	e := &entry{
+		index: index,
+		path:  path,
+	}
+	if s.config.MaxBatchSize > 0 {
+		e.batcher = newBatcher(s.config.MaxBatchSize, s.config.MaxDelay, e.searchBatch)
+	}
+	s.entries[name] = e
+}
+
+// Reload replaces the index served as name with the one saved at the path it
+// was loaded from. Searches in flight finish on the old index.
+func (s *Server) Reload(name string) error {
+	e, err := s.entry(name)
+	if err != nil {
+		return err
+	}
+	path := e.currentPath()
+	if path == "" {
+		return ErrNoIndexPath
+	}
+	index, err := vanadium.LoadFile(path)
+	if err != nil {
+		return err
+	}
+	e.swap(index, path)
+	return nil
+}
+
+// ReloadAll reloads every index that has a file path.
+func (s *Server) ReloadAll() error {
+	var errs []error
+	for _, name := range s.Names() {
+		err := s.Reload(name)
+		if err != nil && !errors.Is(err, ErrNoIndexPath) {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Server) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.entries))
+	for name := range s.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Server) Add(name string, data []float32) error {
+	e, err := s.entry(name)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.index.Add(data)
+}
+
+func (s *Server) Search(name string, query []float32, k int) ([][]int, [][]float32, error) {
+	e, err := s.entry(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Invalid requests are rejected here so they cannot fail a whole batch.
+	numQueries, err := e.validate(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	if k <= 0 {
+		return nil, nil, vanadium.ErrInvalidK
+	}
+	if e.batcher == nil {
+		return e.search(query, k)
+	}
+	return e.batcher.Search(query, k, numQueries)
+}
+
+// RangeSearch returns, for each query, the vectors within radius of it in
+// ascending order of distance.
+func (s *Server) RangeSearch(name string, query []float32, radius float32) ([][]int, [][]float32, error) {
+	e, err := s.entry(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	numQueries, err := e.validate(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	e.mu.RLock()
+	numVectors := e.index.NumVectors()
+	var results [][]int
+	var distances [][]float32
+	if numVectors > 0 {
+		results, distances, err = e.index.Search(query, numVectors)
+	}
+	e.mu.RUnlock()
+	if err != nil {
+		return nil, nil, err
+	}
+	if numVectors == 0 {
+		return make([][]int, numQueries), make([][]float32, numQueries), nil
+	}
+
+	for q := range results {
+		n := sort.Search(len(distances[q]), func(i int) bool {
+			return distances[q][i] > radius
+		})
+		results[q] = results[q][:n]
+		distances[q] = distances[q][:n]
+	}
+	return results, distances, nil
+}
+
+func (s *Server) NumVectors(name string) (int, error) {
+	e, err := s.entry(name)
+	if err != nil {
+		return 0, err
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.index.NumVectors(), nil
+}
+
+func (s *Server) NumFeatures(name string) (int, error) {
+	e, err := s.entry(name)
+	if err != nil {
+		return 0, err
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.index.NumFeatures(), nil
+}
+
+func (s *Server) Info(name string) (vanadium.IndexInfo, error) {
+	e, err := s.entry(name)
+	if err != nil {
+		return vanadium.IndexInfo{}, err
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.index.Info(), nil
+}
+
+// Save writes the index served as name to the path it was loaded from.
+func (s *Server) Save(name string) error {
+	e, err := s.entry(name)
+	if err != nil {
+		return err
+	}
+	path := e.currentPath()
+	if path == "" {
+		return ErrNoIndexPath
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return vanadium.SaveFile(path, e.index)
+}
+
+// Close stops the batchers. It does not save the indexes.
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		if e.batcher != nil {
+			e.batcher.Close()
+		}
+	}
+}
+
+func (s *Server) entry(name string) (*entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.entries[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownIndex, name)
+	}
+	return e, nil
+}
+
+func (e *entry) search(query []float32, k int) ([][]int, [][]float32, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.index.Search(query, k)
+}
+
+// searchBatch searches queries in one Search call. The number of queries in
+// each is taken from the index searched, which a reload may have replaced
+// since the request was validated; queries that no longer fit it fail alone.
+func (e *entry) searchBatch(queries [][]float32, k int) []searchResponse {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	responses := make([]searchResponse, len(queries))
+	numFeatures := e.index.NumFeatures()
+	query := []float32{}
+	for i, q := range queries {
+		if len(q) == 0 || len(q)%numFeatures != 0 {
+			responses[i].err = fmt.Errorf("%w: query has %d values for %d features", vanadium.ErrInvalidDataLength, len(q), numFeatures)
+			continue
+		}
+		query = append(query, q...)
+	}
+	if len(query) == 0 {
+		return responses
+	}
+
+	results, distances, err := e.index.Search(query, k)
+	offset := 0
+	for i, q := range queries {
+		if responses[i].err != nil {
+			continue
+		}
+		if err != nil {
+			responses[i].err = err
+			continue
+		}
+		numQueries := len(q) / numFeatures
+		responses[i].results = results[offset : offset+numQueries]
+		responses[i].distances = distances[offset : offset+numQueries]
+		offset += numQueries
+	}
+	return responses
+}
+
+func (e *entry) validate(query []float32) (int, error) {
+	e.mu.RLock()
+	numFeatures := e.index.NumFeatures()
+	e.mu.RUnlock()
+	if len(query) == 0 {
+		return 0, vanadium.ErrEmptyData
+	}
+	if len(query)%numFeatures != 0 {
+		return 0, vanadium.ErrInvalidDataLength
+	}
+	return len(query) / numFeatures, nil
+}
+
+func (e *entry) swap(index vanadium.ANNIndex, path string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.index = index
+	if path != "" {
+		e.path = path
+	}
+}
+
+func (e *entry) currentPath() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.path
+}
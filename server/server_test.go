@@ -0,0 +1,247 @@
+package server
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	vanadium "github.com/monochromegane/vanadium-index"
+)
+
+func newFlatIndexFile(t *testing.T, data []float32) string {
+	t.Helper()
+	index, err := vanadium.NewIndex(2, vanadium.AsFlat())
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	err = index.Add(data)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "flat.index")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	defer file.Close()
+	err = index.Save(gob.NewEncoder(file))
+	if err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+	return path
+}
+
+func post(t *testing.T, url string, body any, v any) int {
+	t.Helper()
+	b, _ := json.Marshal(body)
+	res, err := http.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("Failed to post %s: %v", url, err)
+	}
+	defer res.Body.Close()
+	if v != nil {
+		err = json.NewDecoder(res.Body).Decode(v)
+		if err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+	return res.StatusCode
+}
+
+func TestServerHTTP(t *testing.T) {
+	path := newFlatIndexFile(t, []float32{0, 0, 1, 1, 2, 2})
+	srv, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+	err = srv.LoadIndex("flat", path)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/healthz")
+	if err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("expected healthy server, got %v %v", res, err)
+	}
+	res.Body.Close()
+
+	var added map[string]int
+	status := post(t, ts.URL+"/indexes/flat/add", vectorsRequest{Vectors: [][]float32{{3, 3}}}, &added)
+	if status != http.StatusOK || added["num_vectors"] != 4 {
+		t.Fatalf("expected 4 vectors, got %d %v", status, added)
+	}
+
+	var found searchResponseBody
+	status = post(t, ts.URL+"/indexes/flat/search", vectorsRequest{Vectors: [][]float32{{3, 3}, {0, 0}}, K: 2}, &found)
+	if status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+	if !reflect.DeepEqual(found.Results, [][]int{{3, 2}, {0, 1}}) {
+		t.Fatalf("unexpected results %v", found.Results)
+	}
+
+	status = post(t, ts.URL+"/indexes/flat/range_search", vectorsRequest{Vectors: [][]float32{{0, 0}}, Radius: 2}, &found)
+	if status != http.StatusOK || !reflect.DeepEqual(found.Results, [][]int{{0, 1}}) {
+		t.Fatalf("unexpected range results %d %v", status, found.Results)
+	}
+
+	var errRes errorResponse
+	status = post(t, ts.URL+"/indexes/flat/search", vectorsRequest{Vectors: [][]float32{{1, 2, 3}}, K: 1}, &errRes)
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d %v", status, errRes)
+	}
+	for _, route := range []string{"add", "search", "range_search"} {
+		status = post(t, ts.URL+"/indexes/flat/"+route, vectorsRequest{Vectors: [][]float32{{1, 2, 3}, {4}}, K: 1, Radius: 1}, &errRes)
+		if status != http.StatusBadRequest {
+			t.Fatalf("expected status 400 for ragged vectors on %s, got %d %v", route, status, errRes)
+		}
+	}
+	status = post(t, ts.URL+"/indexes/missing/search", vectorsRequest{Vectors: [][]float32{{1, 2}}, K: 1}, &errRes)
+	if status != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d %v", status, errRes)
+	}
+
+	status = post(t, ts.URL+"/indexes/flat/save", nil, nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+	status = post(t, ts.URL+"/indexes/flat/reload", nil, nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+	numVectors, _ := srv.NumVectors("flat")
+	if numVectors != 4 {
+		t.Fatalf("expected saved index with 4 vectors, got %d", numVectors)
+	}
+}
+
+func TestServerReload(t *testing.T) {
+	path := newFlatIndexFile(t, []float32{0, 0})
+	srv, _ := New()
+	defer srv.Close()
+	err := srv.LoadIndex("flat", path)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+
+	newPath := newFlatIndexFile(t, []float32{0, 0, 1, 1})
+	err = os.Rename(newPath, path)
+	if err != nil {
+		t.Fatalf("Failed to replace index file: %v", err)
+	}
+	err = srv.ReloadAll()
+	if err != nil {
+		t.Fatalf("Failed to reload index: %v", err)
+	}
+	numVectors, _ := srv.NumVectors("flat")
+	if numVectors != 2 {
+		t.Fatalf("expected reloaded index with 2 vectors, got %d", numVectors)
+	}
+
+	err = srv.Reload("missing")
+	if !errors.Is(err, ErrUnknownIndex) {
+		t.Fatalf("expected %v, got %v", ErrUnknownIndex, err)
+	}
+}
+
+func TestServerMaxRequestBytes(t *testing.T) {
+	path := newFlatIndexFile(t, []float32{0, 0})
+	srv, err := New(WithMaxRequestBytes(64))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+	err = srv.LoadIndex("flat", path)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	vectors := make([][]float32, 32)
+	for i := range vectors {
+		vectors[i] = []float32{1, 1}
+	}
+	var errRes errorResponse
+	status := post(t, ts.URL+"/indexes/flat/add", vectorsRequest{Vectors: vectors}, &errRes)
+	if status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected status 413, got %d %v", status, errRes)
+	}
+	numVectors, _ := srv.NumVectors("flat")
+	if numVectors != 1 {
+		t.Fatalf("expected 1 vector, got %d", numVectors)
+	}
+
+	_, err = New(WithMaxRequestBytes(0))
+	if !errors.Is(err, ErrInvalidMaxRequestBytes) {
+		t.Fatalf("expected %v, got %v", ErrInvalidMaxRequestBytes, err)
+	}
+}
+
+func TestServerBatching(t *testing.T) {
+	numSearches := 0
+	var mu sync.Mutex
+	b := newBatcher(8, 50*time.Millisecond, func(queries [][]float32, k int) []searchResponse {
+		mu.Lock()
+		numSearches++
+		mu.Unlock()
+		responses := make([]searchResponse, len(queries))
+		for i, query := range queries {
+			for _, value := range query {
+				responses[i].results = append(responses[i].results, []int{int(value), k})
+				responses[i].distances = append(responses[i].distances, []float32{value})
+			}
+		}
+		return responses
+	})
+	defer b.Close()
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results, _, err := b.Search([]float32{float32(i)}, 2, 1)
+			if err != nil {
+				t.Errorf("Failed to search: %v", err)
+				return
+			}
+			if !reflect.DeepEqual(results, [][]int{{i, 2}}) {
+				t.Errorf("expected results for query %d, got %v", i, results)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if numSearches >= 8 {
+		t.Fatalf("expected searches to be batched, got %d searches", numSearches)
+	}
+}
+
+func TestServerSearchBatchAfterReload(t *testing.T) {
+	index, _ := vanadium.NewIndex(2, vanadium.AsFlat())
+	index.Add([]float32{0, 0, 1, 1, 2, 2})
+	e := &entry{index: index}
+
+	// Requests validated against an index of 3 features, searched after a
+	// reload swapped in an index of 2 features.
+	responses := e.searchBatch([][]float32{{1, 1, 1}, {2, 2, 0, 0}}, 1)
+	if !errors.Is(responses[0].err, vanadium.ErrInvalidDataLength) {
+		t.Fatalf("expected %v, got %v", vanadium.ErrInvalidDataLength, responses[0].err)
+	}
+	if responses[1].err != nil || !reflect.DeepEqual(responses[1].results, [][]int{{2}, {0}}) {
+		t.Fatalf("unexpected results %v %v", responses[1].results, responses[1].err)
+	}
+}
@@ -0,0 +1,5 @@
+// Package vanadiumpb holds the protocol buffer messages and the gRPC service
+// generated from vanadium.proto.
+package vanadiumpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative vanadium.proto
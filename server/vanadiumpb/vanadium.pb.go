@@ -0,0 +1,876 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: vanadium.proto
+
+package vanadiumpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Vector struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        []float32              `protobuf:"fixed32,1,rep,packed,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Vector) Reset() {
+	*x = Vector{}
+	mi := &file_vanadium_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Vector) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Vector) ProtoMessage() {}
+
+func (x *Vector) ProtoReflect() protoreflect.Message {
+	mi := &file_vanadium_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Vector.ProtoReflect.Descriptor instead.
+func (*Vector) Descriptor() ([]byte, []int) {
+	return file_vanadium_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Vector) GetValues() []float32 {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type Neighbors struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ids           []int64                `protobuf:"varint,1,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	Distances     []float32              `protobuf:"fixed32,2,rep,packed,name=distances,proto3" json:"distances,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Neighbors) Reset() {
+	*x = Neighbors{}
+	mi := &file_vanadium_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Neighbors) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Neighbors) ProtoMessage() {}
+
+func (x *Neighbors) ProtoReflect() protoreflect.Message {
+	mi := &file_vanadium_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Neighbors.ProtoReflect.Descriptor instead.
+func (*Neighbors) Descriptor() ([]byte, []int) {
+	return file_vanadium_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Neighbors) GetIds() []int64 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+func (x *Neighbors) GetDistances() []float32 {
+	if x != nil {
+		return x.Distances
+	}
+	return nil
+}
+
+type ListIndexesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListIndexesRequest) Reset() {
+	*x = ListIndexesRequest{}
+	mi := &file_vanadium_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListIndexesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListIndexesRequest) ProtoMessage() {}
+
+func (x *ListIndexesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_vanadium_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListIndexesRequest.ProtoReflect.Descriptor instead.
+func (*ListIndexesRequest) Descriptor() ([]byte, []int) {
+	return file_vanadium_proto_rawDescGZIP(), []int{2}
+}
+
+type ListIndexesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Indexes       []string               `protobuf:"bytes,1,rep,name=indexes,proto3" json:"indexes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListIndexesResponse) Reset() {
+	*x = ListIndexesResponse{}
+	mi := &file_vanadium_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListIndexesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListIndexesResponse) ProtoMessage() {}
+
+func (x *ListIndexesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_vanadium_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListIndexesResponse.ProtoReflect.Descriptor instead.
+func (*ListIndexesResponse) Descriptor() ([]byte, []int) {
+	return file_vanadium_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListIndexesResponse) GetIndexes() []string {
+	if x != nil {
+		return x.Indexes
+	}
+	return nil
+}
+
+type GetInfoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         string                 `protobuf:"bytes,1,opt,name=index,proto3" json:"index,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetInfoRequest) Reset() {
+	*x = GetInfoRequest{}
+	mi := &file_vanadium_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetInfoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetInfoRequest) ProtoMessage() {}
+
+func (x *GetInfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_vanadium_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetInfoRequest.ProtoReflect.Descriptor instead.
+func (*GetInfoRequest) Descriptor() ([]byte, []int) {
+	return file_vanadium_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetInfoRequest) GetIndex() string {
+	if x != nil {
+		return x.Index
+	}
+	return ""
+}
+
+type GetInfoResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	IndexType     string                 `protobuf:"bytes,1,opt,name=index_type,json=indexType,proto3" json:"index_type,omitempty"`
+	Spec          string                 `protobuf:"bytes,2,opt,name=spec,proto3" json:"spec,omitempty"`
+	NumFeatures   int64                  `protobuf:"varint,3,opt,name=num_features,json=numFeatures,proto3" json:"num_features,omitempty"`
+	NumVectors    int64                  `protobuf:"varint,4,opt,name=num_vectors,json=numVectors,proto3" json:"num_vectors,omitempty"`
+	IsTrained     bool                   `protobuf:"varint,5,opt,name=is_trained,json=isTrained,proto3" json:"is_trained,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetInfoResponse) Reset() {
+	*x = GetInfoResponse{}
+	mi := &file_vanadium_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetInfoResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetInfoResponse) ProtoMessage() {}
+
+func (x *GetInfoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_vanadium_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetInfoResponse.ProtoReflect.Descriptor instead.
+func (*GetInfoResponse) Descriptor() ([]byte, []int) {
+	return file_vanadium_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetInfoResponse) GetIndexType() string {
+	if x != nil {
+		return x.IndexType
+	}
+	return ""
+}
+
+func (x *GetInfoResponse) GetSpec() string {
+	if x != nil {
+		return x.Spec
+	}
+	return ""
+}
+
+func (x *GetInfoResponse) GetNumFeatures() int64 {
+	if x != nil {
+		return x.NumFeatures
+	}
+	return 0
+}
+
+func (x *GetInfoResponse) GetNumVectors() int64 {
+	if x != nil {
+		return x.NumVectors
+	}
+	return 0
+}
+
+func (x *GetInfoResponse) GetIsTrained() bool {
+	if x != nil {
+		return x.IsTrained
+	}
+	return false
+}
+
+type AddRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         string                 `protobuf:"bytes,1,opt,name=index,proto3" json:"index,omitempty"`
+	Vectors       []*Vector              `protobuf:"bytes,2,rep,name=vectors,proto3" json:"vectors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddRequest) Reset() {
+	*x = AddRequest{}
+	mi := &file_vanadium_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddRequest) ProtoMessage() {}
+
+func (x *AddRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_vanadium_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddRequest.ProtoReflect.Descriptor instead.
+func (*AddRequest) Descriptor() ([]byte, []int) {
+	return file_vanadium_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *AddRequest) GetIndex() string {
+	if x != nil {
+		return x.Index
+	}
+	return ""
+}
+
+func (x *AddRequest) GetVectors() []*Vector {
+	if x != nil {
+		return x.Vectors
+	}
+	return nil
+}
+
+type AddResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NumVectors    int64                  `protobuf:"varint,1,opt,name=num_vectors,json=numVectors,proto3" json:"num_vectors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddResponse) Reset() {
+	*x = AddResponse{}
+	mi := &file_vanadium_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddResponse) ProtoMessage() {}
+
+func (x *AddResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_vanadium_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddResponse.ProtoReflect.Descriptor instead.
+func (*AddResponse) Descriptor() ([]byte, []int) {
+	return file_vanadium_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *AddResponse) GetNumVectors() int64 {
+	if x != nil {
+		return x.NumVectors
+	}
+	return 0
+}
+
+type SearchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         string                 `protobuf:"bytes,1,opt,name=index,proto3" json:"index,omitempty"`
+	Vectors       []*Vector              `protobuf:"bytes,2,rep,name=vectors,proto3" json:"vectors,omitempty"`
+	K             int32                  `protobuf:"varint,3,opt,name=k,proto3" json:"k,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	mi := &file_vanadium_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_vanadium_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_vanadium_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SearchRequest) GetIndex() string {
+	if x != nil {
+		return x.Index
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetVectors() []*Vector {
+	if x != nil {
+		return x.Vectors
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetK() int32 {
+	if x != nil {
+		return x.K
+	}
+	return 0
+}
+
+type RangeSearchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         string                 `protobuf:"bytes,1,opt,name=index,proto3" json:"index,omitempty"`
+	Vectors       []*Vector              `protobuf:"bytes,2,rep,name=vectors,proto3" json:"vectors,omitempty"`
+	Radius        float32                `protobuf:"fixed32,3,opt,name=radius,proto3" json:"radius,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RangeSearchRequest) Reset() {
+	*x = RangeSearchRequest{}
+	mi := &file_vanadium_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RangeSearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RangeSearchRequest) ProtoMessage() {}
+
+func (x *RangeSearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_vanadium_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RangeSearchRequest.ProtoReflect.Descriptor instead.
+func (*RangeSearchRequest) Descriptor() ([]byte, []int) {
+	return file_vanadium_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *RangeSearchRequest) GetIndex() string {
+	if x != nil {
+		return x.Index
+	}
+	return ""
+}
+
+func (x *RangeSearchRequest) GetVectors() []*Vector {
+	if x != nil {
+		return x.Vectors
+	}
+	return nil
+}
+
+func (x *RangeSearchRequest) GetRadius() float32 {
+	if x != nil {
+		return x.Radius
+	}
+	return 0
+}
+
+type SearchResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// results holds the neighbors of each query in ascending order of
+	// distance.
+	Results       []*Neighbors `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchResponse) Reset() {
+	*x = SearchResponse{}
+	mi := &file_vanadium_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResponse) ProtoMessage() {}
+
+func (x *SearchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_vanadium_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResponse.ProtoReflect.Descriptor instead.
+func (*SearchResponse) Descriptor() ([]byte, []int) {
+	return file_vanadium_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SearchResponse) GetResults() []*Neighbors {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type SaveRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         string                 `protobuf:"bytes,1,opt,name=index,proto3" json:"index,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SaveRequest) Reset() {
+	*x = SaveRequest{}
+	mi := &file_vanadium_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SaveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SaveRequest) ProtoMessage() {}
+
+func (x *SaveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_vanadium_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SaveRequest.ProtoReflect.Descriptor instead.
+func (*SaveRequest) Descriptor() ([]byte, []int) {
+	return file_vanadium_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SaveRequest) GetIndex() string {
+	if x != nil {
+		return x.Index
+	}
+	return ""
+}
+
+type SaveResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SaveResponse) Reset() {
+	*x = SaveResponse{}
+	mi := &file_vanadium_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SaveResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SaveResponse) ProtoMessage() {}
+
+func (x *SaveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_vanadium_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SaveResponse.ProtoReflect.Descriptor instead.
+func (*SaveResponse) Descriptor() ([]byte, []int) {
+	return file_vanadium_proto_rawDescGZIP(), []int{12}
+}
+
+type ReloadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         string                 `protobuf:"bytes,1,opt,name=index,proto3" json:"index,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReloadRequest) Reset() {
+	*x = ReloadRequest{}
+	mi := &file_vanadium_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReloadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReloadRequest) ProtoMessage() {}
+
+func (x *ReloadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_vanadium_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReloadRequest.ProtoReflect.Descriptor instead.
+func (*ReloadRequest) Descriptor() ([]byte, []int) {
+	return file_vanadium_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ReloadRequest) GetIndex() string {
+	if x != nil {
+		return x.Index
+	}
+	return ""
+}
+
+type ReloadResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReloadResponse) Reset() {
+	*x = ReloadResponse{}
+	mi := &file_vanadium_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReloadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReloadResponse) ProtoMessage() {}
+
+func (x *ReloadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_vanadium_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReloadResponse.ProtoReflect.Descriptor instead.
+func (*ReloadResponse) Descriptor() ([]byte, []int) {
+	return file_vanadium_proto_rawDescGZIP(), []int{14}
+}
+
+var File_vanadium_proto protoreflect.FileDescriptor
+
+const file_vanadium_proto_rawDesc = "" +
+	"\n" +
+	"\x0evanadium.proto\x12\vvanadium.v1\" \n" +
+	"\x06Vector\x12\x16\n" +
+	"\x06values\x18\x01 \x03(\x02R\x06values\";\n" +
+	"\tNeighbors\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\x03R\x03ids\x12\x1c\n" +
+	"\tdistances\x18\x02 \x03(\x02R\tdistances\"\x14\n" +
+	"\x12ListIndexesRequest\"/\n" +
+	"\x13ListIndexesResponse\x12\x18\n" +
+	"\aindexes\x18\x01 \x03(\tR\aindexes\"&\n" +
+	"\x0eGetInfoRequest\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\tR\x05index\"\xa7\x01\n" +
+	"\x0fGetInfoResponse\x12\x1d\n" +
+	"\n" +
+	"index_type\x18\x01 \x01(\tR\tindexType\x12\x12\n" +
+	"\x04spec\x18\x02 \x01(\tR\x04spec\x12!\n" +
+	"\fnum_features\x18\x03 \x01(\x03R\vnumFeatures\x12\x1f\n" +
+	"\vnum_vectors\x18\x04 \x01(\x03R\n" +
+	"numVectors\x12\x1d\n" +
+	"\n" +
+	"is_trained\x18\x05 \x01(\bR\tisTrained\"Q\n" +
+	"\n" +
+	"AddRequest\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\tR\x05index\x12-\n" +
+	"\avectors\x18\x02 \x03(\v2\x13.vanadium.v1.VectorR\avectors\".\n" +
+	"\vAddResponse\x12\x1f\n" +
+	"\vnum_vectors\x18\x01 \x01(\x03R\n" +
+	"numVectors\"b\n" +
+	"\rSearchRequest\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\tR\x05index\x12-\n" +
+	"\avectors\x18\x02 \x03(\v2\x13.vanadium.v1.VectorR\avectors\x12\f\n" +
+	"\x01k\x18\x03 \x01(\x05R\x01k\"q\n" +
+	"\x12RangeSearchRequest\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\tR\x05index\x12-\n" +
+	"\avectors\x18\x02 \x03(\v2\x13.vanadium.v1.VectorR\avectors\x12\x16\n" +
+	"\x06radius\x18\x03 \x01(\x02R\x06radius\"B\n" +
+	"\x0eSearchResponse\x120\n" +
+	"\aresults\x18\x01 \x03(\v2\x16.vanadium.v1.NeighborsR\aresults\"/\n" +
+	"\vSaveRequest\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\tR\x05indexJ\x04\b\x02\x10\x03R\x04path\"\x0e\n" +
+	"\fSaveResponse\"1\n" +
+	"\rReloadRequest\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\tR\x05indexJ\x04\b\x02\x10\x03R\x04path\"\x10\n" +
+	"\x0eReloadResponse2\xec\x03\n" +
+	"\bVanadium\x12P\n" +
+	"\vListIndexes\x12\x1f.vanadium.v1.ListIndexesRequest\x1a .vanadium.v1.ListIndexesResponse\x12D\n" +
+	"\aGetInfo\x12\x1b.vanadium.v1.GetInfoRequest\x1a\x1c.vanadium.v1.GetInfoResponse\x128\n" +
+	"\x03Add\x12\x17.vanadium.v1.AddRequest\x1a\x18.vanadium.v1.AddResponse\x12A\n" +
+	"\x06Search\x12\x1a.vanadium.v1.SearchRequest\x1a\x1b.vanadium.v1.SearchResponse\x12K\n" +
+	"\vRangeSearch\x12\x1f.vanadium.v1.RangeSearchRequest\x1a\x1b.vanadium.v1.SearchResponse\x12;\n" +
+	"\x04Save\x12\x18.vanadium.v1.SaveRequest\x1a\x19.vanadium.v1.SaveResponse\x12A\n" +
+	"\x06Reload\x12\x1a.vanadium.v1.ReloadRequest\x1a\x1b.vanadium.v1.ReloadResponseB<Z:github.com/monochromegane/vanadium-index/server/vanadiumpbb\x06proto3"
+
+var (
+	file_vanadium_proto_rawDescOnce sync.Once
+	file_vanadium_proto_rawDescData []byte
+)
+
+func file_vanadium_proto_rawDescGZIP() []byte {
+	file_vanadium_proto_rawDescOnce.Do(func() {
+		file_vanadium_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_vanadium_proto_rawDesc), len(file_vanadium_proto_rawDesc)))
+	})
+	return file_vanadium_proto_rawDescData
+}
+
+var file_vanadium_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_vanadium_proto_goTypes = []any{
+	(*Vector)(nil),              // 0: vanadium.v1.Vector
+	(*Neighbors)(nil),           // 1: vanadium.v1.Neighbors
+	(*ListIndexesRequest)(nil),  // 2: vanadium.v1.ListIndexesRequest
+	(*ListIndexesResponse)(nil), // 3: vanadium.v1.ListIndexesResponse
+	(*GetInfoRequest)(nil),      // 4: vanadium.v1.GetInfoRequest
+	(*GetInfoResponse)(nil),     // 5: vanadium.v1.GetInfoResponse
+	(*AddRequest)(nil),          // 6: vanadium.v1.AddRequest
+	(*AddResponse)(nil),         // 7: vanadium.v1.AddResponse
+	(*SearchRequest)(nil),       // 8: vanadium.v1.SearchRequest
+	(*RangeSearchRequest)(nil),  // 9: vanadium.v1.RangeSearchRequest
+	(*SearchResponse)(nil),      // 10: vanadium.v1.SearchResponse
+	(*SaveRequest)(nil),         // 11: vanadium.v1.SaveRequest
+	(*SaveResponse)(nil),        // 12: vanadium.v1.SaveResponse
+	(*ReloadRequest)(nil),       // 13: vanadium.v1.ReloadRequest
+	(*ReloadResponse)(nil),      // 14: vanadium.v1.ReloadResponse
+}
+var file_vanadium_proto_depIdxs = []int32{
+	0,  // 0: vanadium.v1.AddRequest.vectors:type_name -> vanadium.v1.Vector
+	0,  // 1: vanadium.v1.SearchRequest.vectors:type_name -> vanadium.v1.Vector
+	0,  // 2: vanadium.v1.RangeSearchRequest.vectors:type_name -> vanadium.v1.Vector
+	1,  // 3: vanadium.v1.SearchResponse.results:type_name -> vanadium.v1.Neighbors
+	2,  // 4: vanadium.v1.Vanadium.ListIndexes:input_type -> vanadium.v1.ListIndexesRequest
+	4,  // 5: vanadium.v1.Vanadium.GetInfo:input_type -> vanadium.v1.GetInfoRequest
+	6,  // 6: vanadium.v1.Vanadium.Add:input_type -> vanadium.v1.AddRequest
+	8,  // 7: vanadium.v1.Vanadium.Search:input_type -> vanadium.v1.SearchRequest
+	9,  // 8: vanadium.v1.Vanadium.RangeSearch:input_type -> vanadium.v1.RangeSearchRequest
+	11, // 9: vanadium.v1.Vanadium.Save:input_type -> vanadium.v1.SaveRequest
+	13, // 10: vanadium.v1.Vanadium.Reload:input_type -> vanadium.v1.ReloadRequest
+	3,  // 11: vanadium.v1.Vanadium.ListIndexes:output_type -> vanadium.v1.ListIndexesResponse
+	5,  // 12: vanadium.v1.Vanadium.GetInfo:output_type -> vanadium.v1.GetInfoResponse
+	7,  // 13: vanadium.v1.Vanadium.Add:output_type -> vanadium.v1.AddResponse
+	10, // 14: vanadium.v1.Vanadium.Search:output_type -> vanadium.v1.SearchResponse
+	10, // 15: vanadium.v1.Vanadium.RangeSearch:output_type -> vanadium.v1.SearchResponse
+	12, // 16: vanadium.v1.Vanadium.Save:output_type -> vanadium.v1.SaveResponse
+	14, // 17: vanadium.v1.Vanadium.Reload:output_type -> vanadium.v1.ReloadResponse
+	11, // [11:18] is the sub-list for method output_type
+	4,  // [4:11] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_vanadium_proto_init() }
+func file_vanadium_proto_init() {
+	if File_vanadium_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_vanadium_proto_rawDesc), len(file_vanadium_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   15,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_vanadium_proto_goTypes,
+		DependencyIndexes: file_vanadium_proto_depIdxs,
+		MessageInfos:      file_vanadium_proto_msgTypes,
+	}.Build()
+	File_vanadium_proto = out.File
+	file_vanadium_proto_goTypes = nil
+	file_vanadium_proto_depIdxs = nil
+}
@@ -0,0 +1,534 @@
+package vanadium_index
+
+import (
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+type PartitionMethod int
+
+const (
+	PartitionRoundRobin PartitionMethod = iota
+	PartitionHash
+)
+
+const (
+	shardedManifestName = "manifest"
+	shardedFilePrefix   = "shard-"
+)
+
+// ShardedIndex spreads vectors over child indexes that share one trained
+// model. Search queries every shard concurrently and merges their top-k.
+type ShardedIndex struct {
+	state  *ShardedIndexState
+	shards []ANNIndex
+}
+
+type ShardedIndexState struct {
+	NumFeatures int
+	NumShards   int
+	IsTrained   bool
+	Config      *ShardedIndexConfig
+	// Mapping holds the global id of each vector of each shard.
+	Mapping [][]int
+	// IsMappingDelta reports that Mapping was saved delta-encoded.
+	IsMappingDelta bool
+	// ShardFiles lists the shard files next to a manifest written by
+	// SaveDir. It is empty when the shards are stored in the same stream.
+	ShardFiles []string
+}
+
+type ShardedIndexConfig struct {
+	Partition PartitionMethod
+}
+
+func newShardedIndex(numFeatures int, shards []ANNIndex, opts ...ShardedIndexOption) (*ShardedIndex, error) {
+	if numFeatures <= 0 {
+		return nil, ErrInvalidNumFeatures
+	}
+	if len(shards) == 0 {
+		return nil, ErrInvalidNumShards
+	}
+
+	index := &ShardedIndex{
+		state: &ShardedIndexState{
+			NumFeatures: numFeatures,
+			NumShards:   len(shards),
+			IsTrained:   shards[0].Info().IsTrained,
+			// Default values
+			Config: &ShardedIndexConfig{
+				Partition: PartitionRoundRobin,
+			},
+			Mapping: make([][]int, len(shards)),
+		},
+		shards: shards,
+	}
+	for _, opt := range opts {
+		err := opt(index.state.Config)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return index, nil
+}
+
+func loadShardedIndex(dec *gob.Decoder) (*ShardedIndex, error) {
+	index := &ShardedIndex{}
+	err := index.decode(dec)
+	if err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// LoadShardedIndex loads a sharded index written by SaveDir.
+func LoadShardedIndex(dir string) (*ShardedIndex, error) {
+	index := &ShardedIndex{}
+	err := readFile(filepath.Join(dir, shardedManifestName), func(r io.Reader) error {
+		dec := gob.NewDecoder(r)
+		var meta MetaData
+		err := dec.Decode(&meta)
+		if err != nil {
+			return err
+		}
+		if meta.IndexType != IndexTypeSharded {
+			return fmt.Errorf("%w: %s", ErrUnknownIndexType, meta.IndexType)
+		}
+		return index.decodeState(dec)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(index.state.ShardFiles) != index.state.NumShards {
+		return nil, fmt.Errorf("%w: %d shard files for %d shards", ErrInvalidManifest, len(index.state.ShardFiles), index.state.NumShards)
+	}
+	for _, name := range index.state.ShardFiles {
+		if name == "" || name == "." || name == ".." || filepath.Base(name) != name {
+			return nil, fmt.Errorf("%w: shard file %q is not in %s", ErrInvalidManifest, name, dir)
+		}
+	}
+
+	index.shards = make([]ANNIndex, index.state.NumShards)
+	for s, name := range index.state.ShardFiles {
+		index.shards[s], err = LoadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+	}
+	index.state.ShardFiles = nil
+	err = index.checkMapping()
+	if err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// Train trains the first shard and copies its model to the other shards, so
+// that all shards quantize vectors the same way and can be merged. It fails
+// with ErrIndexNotEmpty once vectors have been added.
+func (index *ShardedIndex) Train(data []float32) error {
+	if len(data) == 0 {
+		return newIndexError(OpTrain, IndexTypeSharded, ErrEmptyData)
+	}
+
+	if len(data)%index.state.NumFeatures != 0 {
+		return newIndexError(OpTrain, IndexTypeSharded, ErrInvalidDataLength, "dataLength", len(data), "numFeatures", index.state.NumFeatures)
+	}
+
+	// Vectors already added were quantized with the old model and cannot be
+	// moved to the new one, and the copies below would drop them.
+	if index.NumVectors() > 0 {
+		return newIndexError(OpTrain, IndexTypeSharded, ErrIndexNotEmpty, "numVectors", index.NumVectors())
+	}
+
+	err := index.shards[0].Train(data)
+	if err != nil {
+		return err
+	}
+
+	for s := 1; s < index.state.NumShards; s++ {
+		shard, err := index.shards[0].emptyCopy()
+		if err != nil {
+			return err
+		}
+		index.shards[s] = shard
+	}
+
+	index.state.IsTrained = true
+	return nil
+}
+
+func (index *ShardedIndex) Add(data []float32) error {
+	if len(data) == 0 {
+		return newIndexError(OpAdd, IndexTypeSharded, ErrEmptyData)
+	}
+
+	if len(data)%index.state.NumFeatures != 0 {
+		return newIndexError(OpAdd, IndexTypeSharded, ErrInvalidDataLength, "dataLength", len(data), "numFeatures", index.state.NumFeatures)
+	}
+
+	numVectors := len(data) / index.state.NumFeatures
+	offset := index.nextID()
+	shardData := make([][]float32, index.state.NumShards)
+	shardIds := make([][]int, index.state.NumShards)
+	for v := range numVectors {
+		vector := data[v*index.state.NumFeatures : (v+1)*index.state.NumFeatures]
+		s := index.partition(offset+v, vector)
+		shardData[s] = append(shardData[s], vector...)
+		shardIds[s] = append(shardIds[s], offset+v)
+	}
+
+	var eg errgroup.Group
+	eg.SetLimit(runtime.NumCPU())
+	for s := range index.state.NumShards {
+		if len(shardData[s]) == 0 {
+			continue
+		}
+		eg.Go(func() error {
+			err := index.shards[s].Add(shardData[s])
+			if err != nil {
+				return err
+			}
+			// Each shard records its ids as soon as it holds the vectors, so
+			// that a shard failing does not leave the others unmapped.
+			index.state.Mapping[s] = append(index.state.Mapping[s], shardIds[s]...)
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+// nextID returns the id following the largest id in Mapping. It equals
+// NumVectors unless an Add failed on some shards; the ids given to the vectors
+// that failed are then skipped, so that ids stay unique. The ids of each shard
+// are ascending.
+func (index *ShardedIndex) nextID() int {
+	next := 0
+	for _, ids := range index.state.Mapping {
+		if len(ids) > 0 {
+			next = max(next, ids[len(ids)-1]+1)
+		}
+	}
+	return next
+}
+
+func (index *ShardedIndex) Search(query []float32, k int) ([][]int, [][]float32, error) {
+	if k <= 0 {
+		return nil, nil, newIndexError(OpSearch, IndexTypeSharded, ErrInvalidK, "k", k)
+	}
+
+	if len(query) == 0 {
+		return nil, nil, newIndexError(OpSearch, IndexTypeSharded, ErrEmptyData)
+	}
+
+	if len(query)%index.state.NumFeatures != 0 {
+		return nil, nil, newIndexError(OpSearch, IndexTypeSharded, ErrInvalidDataLength, "dataLength", len(query), "numFeatures", index.state.NumFeatures)
+	}
+
+	shardResults := make([][][]int, index.state.NumShards)
+	shardDistances := make([][][]float32, index.state.NumShards)
+
+	var eg errgroup.Group
+	eg.SetLimit(runtime.NumCPU())
+	for s := range index.state.NumShards {
+		if index.shards[s].NumVectors() == 0 {
+			continue
+		}
+		eg.Go(func() error {
+			var err error
+			shardResults[s], shardDistances[s], err = index.shards[s].Search(query, k)
+			return err
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	numQueries := len(query) / index.state.NumFeatures
+	results := make([][]int, numQueries)
+	distances := make([][]float32, numQueries)
+	for q := range numQueries {
+		neighbors := NewSmallestK(k)
+		for s := range index.state.NumShards {
+			if shardResults[s] == nil {
+				continue
+			}
+			for i, r := range shardResults[s][q] {
+				neighbors.Push(index.state.Mapping[s][r], shardDistances[s][q][i])
+			}
+		}
+
+		smallestK := neighbors.SmallestK()
+		results[q] = make([]int, len(smallestK))
+		distances[q] = make([]float32, len(smallestK))
+		for i, item := range smallestK {
+			results[q][i] = item.index
+			distances[q][i] = item.value
+		}
+	}
+
+	return results, distances, nil
+}
+
+func (index *ShardedIndex) NumVectors() int {
+	numVectors := 0
+	for _, shard := range index.shards {
+		numVectors += shard.NumVectors()
+	}
+	return numVectors
+}
+
+func (index *ShardedIndex) NumFeatures() int {
+	return index.state.NumFeatures
+}
+
+func (index *ShardedIndex) Describe() string {
+	prefix := "Shard"
+	if index.state.Config.Partition == PartitionHash {
+		prefix = "ShardHash"
+	}
+	return fmt.Sprintf("%s%d:%s", prefix, index.state.NumShards, index.shards[0].Describe())
+}
+
+func (index *ShardedIndex) Info() IndexInfo {
+	sub := index.shards[0].Info()
+	sub.NumVectors = 0
+	sub.Memory = MemoryUsage{}
+	for _, shard := range index.shards {
+		shardInfo := shard.Info()
+		sub.NumVectors += shardInfo.NumVectors
+		sub.Memory = sub.Memory.add(shardInfo.Memory)
+	}
+
+	memory := MemoryUsage{
+		Mapping: mappingSize(index.state.Mapping),
+	}.withTotal()
+	return IndexInfo{
+		IndexType:   IndexTypeSharded,
+		CodeType1:   CodeTypeNameNone,
+		CodeType2:   CodeTypeNameNone,
+		Spec:        index.Describe(),
+		NumFeatures: index.state.NumFeatures,
+		NumVectors:  index.NumVectors(),
+		IsTrained:   sub.IsTrained,
+		NumShards:   index.state.NumShards,
+		Memory:      memory.add(sub.Memory),
+		Sub:         &sub,
+	}
+}
+
+// emptyCopy returns a new index whose shards are empty copies of the shards
+// of index.
+func (index *ShardedIndex) emptyCopy() (ANNIndex, error) {
+	state := *index.state
+	config := *index.state.Config
+	state.Config = &config
+	state.Mapping = make([][]int, index.state.NumShards)
+	state.ShardFiles = nil
+
+	shards := make([]ANNIndex, len(index.shards))
+	for s, shard := range index.shards {
+		var err error
+		shards[s], err = shard.emptyCopy()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &ShardedIndex{state: &state, shards: shards}, nil
+}
+
+// Merge merges each shard of other into the matching shard of index. The ids
+// of other are shifted past the ids of index.
+func (index *ShardedIndex) Merge(other ANNIndex) error {
+	err := index.checkMerge(other)
+	if err != nil {
+		return err
+	}
+	o := other.(*ShardedIndex)
+
+	offset := index.nextID()
+	for s := range index.state.NumShards {
+		otherMapping := o.state.Mapping[s]
+		err = index.shards[s].Merge(o.shards[s])
+		if err != nil {
+			return err
+		}
+		for _, id := range otherMapping {
+			index.state.Mapping[s] = append(index.state.Mapping[s], offset+id)
+		}
+	}
+	return nil
+}
+
+func (index *ShardedIndex) checkMerge(other ANNIndex) error {
+	o, ok := other.(*ShardedIndex)
+	if !ok {
+		return newIndexError(OpMerge, IndexTypeSharded, ErrIncompatibleIndex)
+	}
+	if o.state.NumShards != index.state.NumShards {
+		return newIndexError(OpMerge, IndexTypeSharded, ErrIncompatibleIndex, "numShards", index.state.NumShards, "otherNumShards", o.state.NumShards)
+	}
+	for s := range index.state.NumShards {
+		err := index.shards[s].checkMerge(o.shards[s])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (index *ShardedIndex) Save(enc *gob.Encoder) error {
+	err := enc.Encode(index.metaData())
+	if err != nil {
+		return err
+	}
+	return index.encode(enc)
+}
+
+// SaveDir writes a manifest and one file per shard into dir, so that shards
+// can be copied or loaded independently. opts apply to each shard file.
+//
+// Shard files are named after the save that wrote them and only the new
+// manifest points to them, so a crash before the manifest is replaced leaves
+// the previous save intact. Shard files of previous saves are removed once the
+// manifest is written.
+func (index *ShardedIndex) SaveDir(dir string, opts ...SaveOption) error {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+
+	generation := strconv.FormatInt(time.Now().UnixNano(), 36)
+	state := index.encodedState()
+	state.ShardFiles = make([]string, index.state.NumShards)
+	for s, shard := range index.shards {
+		state.ShardFiles[s] = fmt.Sprintf("%s%04d-%s", shardedFilePrefix, s, generation)
+		err = SaveFile(filepath.Join(dir, state.ShardFiles[s]), shard, opts...)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = writeFileAtomic(filepath.Join(dir, shardedManifestName), func(w io.Writer) error {
+		enc := gob.NewEncoder(w)
+		err := enc.Encode(index.metaData())
+		if err != nil {
+			return err
+		}
+		return enc.Encode(state)
+	})
+	if err != nil {
+		return err
+	}
+
+	stale, _ := filepath.Glob(filepath.Join(dir, shardedFilePrefix+"*"))
+	for _, path := range stale {
+		if !slices.Contains(state.ShardFiles, filepath.Base(path)) {
+			os.Remove(path)
+		}
+	}
+	return nil
+}
+
+func (index *ShardedIndex) metaData() MetaData {
+	return MetaData{
+		IndexType: IndexTypeSharded,
+		CodeType1: CodeTypeNameNone,
+		CodeType2: CodeTypeNameNone,
+	}
+}
+
+// encodedState returns a copy of the state with Mapping delta-encoded.
+func (index *ShardedIndex) encodedState() *ShardedIndexState {
+	state := *index.state
+	state.Mapping = deltaEncodeMapping(index.state.Mapping)
+	state.IsMappingDelta = true
+	return &state
+}
+
+func (index *ShardedIndex) encode(enc *gob.Encoder) error {
+	err := enc.Encode(index.encodedState())
+	if err != nil {
+		return err
+	}
+	for _, shard := range index.shards {
+		err = shard.Save(enc)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (index *ShardedIndex) decode(dec *gob.Decoder) error {
+	err := index.decodeState(dec)
+	if err != nil {
+		return err
+	}
+	if len(index.state.ShardFiles) > 0 {
+		return fmt.Errorf("%w: load it with LoadShardedIndex", ErrInvalidManifest)
+	}
+
+	index.shards = make([]ANNIndex, index.state.NumShards)
+	for s := range index.state.NumShards {
+		index.shards[s], err = LoadIndex(dec)
+		if err != nil {
+			return err
+		}
+	}
+	return index.checkMapping()
+}
+
+// checkMapping checks that Mapping holds an id for every vector of each
+// loaded shard.
+func (index *ShardedIndex) checkMapping() error {
+	if len(index.state.Mapping) != index.state.NumShards {
+		return fmt.Errorf("%w: mapping has %d shards, index has %d", ErrInvalidManifest, len(index.state.Mapping), index.state.NumShards)
+	}
+	for s, shard := range index.shards {
+		if len(index.state.Mapping[s]) != shard.NumVectors() {
+			return fmt.Errorf("%w: mapping has %d ids for the %d vectors of shard %d", ErrInvalidManifest, len(index.state.Mapping[s]), shard.NumVectors(), s)
+		}
+	}
+	return nil
+}
+
+func (index *ShardedIndex) decodeState(dec *gob.Decoder) error {
+	index.state = &ShardedIndexState{
+		Config: &ShardedIndexConfig{},
+	}
+	err := dec.Decode(index.state)
+	if err != nil {
+		return err
+	}
+	if index.state.IsMappingDelta {
+		deltaDecodeMapping(index.state.Mapping)
+		index.state.IsMappingDelta = false
+	}
+	return nil
+}
+
+func (index *ShardedIndex) partition(id int, vector []float32) int {
+	if index.state.Config.Partition == PartitionRoundRobin {
+		return id % index.state.NumShards
+	}
+	h := fnv.New64a()
+	var buf [4]byte
+	for _, value := range vector {
+		binary.LittleEndian.PutUint32(buf[:], math.Float32bits(value))
+		h.Write(buf[:])
+	}
+	return int(h.Sum64() % uint64(index.state.NumShards))
+}
@@ -0,0 +1,13 @@
+package vanadium_index
+
+type ShardedIndexOption func(*ShardedIndexConfig) error
+
+// WithShardedHashPartition assigns each added vector to a shard by a hash of
+// its values instead of round-robin, so the same vector always lands in the
+// same shard.
+func WithShardedHashPartition() ShardedIndexOption {
+	return func(config *ShardedIndexConfig) error {
+		config.Partition = PartitionHash
+		return nil
+	}
+}
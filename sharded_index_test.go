@@ -0,0 +1,271 @@
+package vanadium_index
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestShardedIndex(t *testing.T) {
+	numFeatures := 4
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 32*numFeatures)
+	for i := range data {
+		data[i] = rng.Float32()
+	}
+
+	for _, opts := range [][]ShardedIndexOption{nil, {WithShardedHashPartition()}} {
+		index, err := NewIndex(numFeatures, AsSharded(3, AsFlat(), opts...))
+		if err != nil {
+			t.Fatalf("Failed to create index: %v", err)
+		}
+		err = index.Train(data)
+		if err != nil {
+			t.Fatalf("Failed to train index: %v", err)
+		}
+		for i := 0; i < len(data); i += 8 * numFeatures {
+			err = index.Add(data[i : i+8*numFeatures])
+			if err != nil {
+				t.Fatalf("Failed to add data: %v", err)
+			}
+		}
+		if index.NumVectors() != 32 {
+			t.Fatalf("expected 32 vectors, got %d", index.NumVectors())
+		}
+
+		flat, _ := NewIndex(numFeatures, AsFlat())
+		flat.Add(data)
+		expectedResults, expectedDistances, _ := flat.Search(data, 5)
+		results, distances, err := index.Search(data, 5)
+		if err != nil {
+			t.Fatalf("Failed to search index: %v", err)
+		}
+		if !reflect.DeepEqual(results, expectedResults) || !reflect.DeepEqual(distances, expectedDistances) {
+			t.Fatalf("expected %v %v, got %v %v", expectedResults, expectedDistances, results, distances)
+		}
+	}
+}
+
+func TestShardedIndexSharesModel(t *testing.T) {
+	numFeatures := 4
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 32*numFeatures)
+	for i := range data {
+		data[i] = rng.Float32()
+	}
+
+	index, err := NewIndex(numFeatures, AsSharded(2, AsPQ(2, 4, WithPQMaxIterations(10), WithPQSeed(1))))
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	err = index.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+	err = index.Add(data)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+
+	sharded := index.(*ShardedIndex)
+	err = sharded.shards[0].checkMerge(sharded.shards[1])
+	if err != nil {
+		t.Fatalf("expected shards to share the model: %v", err)
+	}
+	if sharded.shards[0].NumVectors() != 16 || sharded.shards[1].NumVectors() != 16 {
+		t.Fatalf("expected 16 vectors per shard, got %d and %d", sharded.shards[0].NumVectors(), sharded.shards[1].NumVectors())
+	}
+	if index.Describe() != "Shard2:PQ2x2" || index.Info().NumShards != 2 {
+		t.Fatalf("unexpected description %q", index.Describe())
+	}
+}
+
+func TestShardedIndexTrainAfterAdd(t *testing.T) {
+	index, _ := NewIndex(2, AsSharded(2, AsFlat()))
+	err := index.Add([]float32{0, 0, 1, 1, 2, 2, 3, 3, 4, 4})
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	err = index.Train([]float32{0, 0})
+	if !errors.Is(err, ErrIndexNotEmpty) {
+		t.Fatalf("expected %v, got %v", ErrIndexNotEmpty, err)
+	}
+
+	results, _, err := index.Search([]float32{0, 0}, 5)
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if !reflect.DeepEqual(results, [][]int{{0, 1, 2, 3, 4}}) {
+		t.Fatalf("expected all vectors to survive, got %v", results)
+	}
+}
+
+func TestShardedIndexAddPartialFailure(t *testing.T) {
+	flat, _ := newFlatIndex(2)
+	pq, _ := NewIndex(2, AsPQ(1, 2))
+	index, err := newShardedIndex(2, []ANNIndex{flat, pq})
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+
+	// The untrained PQ shard fails while the flat shard takes its vectors.
+	err = index.Add([]float32{0, 0, 1, 1, 2, 2, 3, 3})
+	if !errors.Is(err, ErrNotTrained) {
+		t.Fatalf("expected %v, got %v", ErrNotTrained, err)
+	}
+	results, _, err := index.Search([]float32{2, 2}, 4)
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if !reflect.DeepEqual(results, [][]int{{2, 0}}) {
+		t.Fatalf("unexpected results %v", results)
+	}
+
+	// Later ids follow the ids in use, so they do not repeat id 2.
+	err = index.Add([]float32{4, 4, 5, 5})
+	if !errors.Is(err, ErrNotTrained) {
+		t.Fatalf("expected %v, got %v", ErrNotTrained, err)
+	}
+	results, _, _ = index.Search([]float32{5, 5}, 4)
+	if !reflect.DeepEqual(results, [][]int{{4, 2, 0}}) {
+		t.Fatalf("unexpected results %v", results)
+	}
+}
+
+func TestShardedIndexSaveLoad(t *testing.T) {
+	numFeatures := 4
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 32*numFeatures)
+	for i := range data {
+		data[i] = rng.Float32()
+	}
+
+	index, err := NewIndexFromSpec(numFeatures, "ShardHash2:IVF2,Flat")
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	err = index.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+	err = index.Add(data)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	expectedResults, expectedDistances, _ := index.Search(data, 3)
+
+	var buf bytes.Buffer
+	err = index.Save(gob.NewEncoder(&buf))
+	if err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+	loaded, err := LoadIndex(gob.NewDecoder(&buf))
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+
+	dir := t.TempDir()
+	err = index.(*ShardedIndex).SaveDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+	loadedDir, err := LoadShardedIndex(dir)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+
+	for _, loaded := range []ANNIndex{loaded, loadedDir} {
+		if loaded.Describe() != "ShardHash2:IVF2,Flat" {
+			t.Fatalf("unexpected description %q", loaded.Describe())
+		}
+		results, distances, err := loaded.Search(data, 3)
+		if err != nil {
+			t.Fatalf("Failed to search index: %v", err)
+		}
+		if !reflect.DeepEqual(results, expectedResults) || !reflect.DeepEqual(distances, expectedDistances) {
+			t.Fatalf("expected %v %v, got %v %v", expectedResults, expectedDistances, results, distances)
+		}
+	}
+
+	_, err = NewIndex(numFeatures, AsSharded(0, AsFlat()))
+	if !errors.Is(err, ErrInvalidNumShards) {
+		t.Fatalf("expected %v, got %v", ErrInvalidNumShards, err)
+	}
+}
+
+func TestShardedIndexSaveDir(t *testing.T) {
+	numFeatures := 2
+	index, _ := NewIndex(numFeatures, AsSharded(2, AsFlat()))
+	index.Train([]float32{0, 0})
+	index.Add([]float32{0, 0, 1, 1, 2, 2})
+	sharded := index.(*ShardedIndex)
+
+	dir := t.TempDir()
+	for range 2 {
+		err := sharded.SaveDir(dir)
+		if err != nil {
+			t.Fatalf("Failed to save index: %v", err)
+		}
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 3 {
+		t.Fatalf("expected the manifest and 2 shard files, got %d files", len(entries))
+	}
+
+	writeManifest := func(t *testing.T, state *ShardedIndexState) {
+		t.Helper()
+		err := writeFileAtomic(filepath.Join(dir, shardedManifestName), func(w io.Writer) error {
+			enc := gob.NewEncoder(w)
+			err := enc.Encode(sharded.metaData())
+			if err != nil {
+				return err
+			}
+			return enc.Encode(state)
+		})
+		if err != nil {
+			t.Fatalf("Failed to write manifest: %v", err)
+		}
+	}
+
+	t.Run("shard file outside dir", func(t *testing.T) {
+		state := sharded.encodedState()
+		state.ShardFiles = []string{"../shard-0000", entries[1].Name()}
+		writeManifest(t, state)
+		_, err := LoadShardedIndex(dir)
+		if !errors.Is(err, ErrInvalidManifest) {
+			t.Fatalf("expected %v, got %v", ErrInvalidManifest, err)
+		}
+	})
+
+	t.Run("mapping of other shard files", func(t *testing.T) {
+		state := sharded.encodedState()
+		state.ShardFiles = []string{entries[1].Name(), entries[1].Name()}
+		writeManifest(t, state)
+		_, err := LoadShardedIndex(dir)
+		if !errors.Is(err, ErrInvalidManifest) {
+			t.Fatalf("expected %v, got %v", ErrInvalidManifest, err)
+		}
+	})
+}
+
+func TestShardedIndexMerge(t *testing.T) {
+	numFeatures := 4
+	rng := rand.New(rand.NewPCG(1, 2))
+	data := make([]float32, 32*numFeatures)
+	for i := range data {
+		data[i] = rng.Float32()
+	}
+
+	model, _ := NewIndex(numFeatures, AsSharded(2, AsPQ(2, 4, WithPQMaxIterations(10), WithPQSeed(1))))
+	err := model.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+	testMerge(t, model, data, numFeatures)
+}
@@ -0,0 +1,235 @@
+package vanadium_index
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Index specs describe an index as a comma-separated factory string in the
+// style of FAISS, e.g. "Flat", "PQ16x8", "IVF1024,Flat", "IVF1024,PQ16x8" or
+// "IVF1024_HNSW32,PQ16x8,Refine4".
+//
+//   - PQ{M}x{nbits} is a product quantizer with M subspaces of 2^nbits
+//     clusters each. PQ{M} uses 8 bits and PQ{M}c{n} uses n clusters, which
+//     need not be a power of two.
+//   - IVF{nlist} is an inverted file with nlist lists followed by the index
+//     used within each list. IVF{nlist}_HNSW{M} searches the coarse centroids
+//     with an HNSW graph, optionally with efConstruction and efSearch as
+//     IVF{nlist}_HNSW{M}x{efConstruction}x{efSearch}.
+//   - A trailing Refine{k} reranks k times as many candidates with exact
+//     distances, or with 8-bit scalar quantized vectors as Refine{k}(SQ8).
+//
+// A spec prefixed with Shard{n}: spreads vectors round-robin over n shards of
+// the index that follows, or by a hash of each vector with ShardHash{n}:.
+//
+// Binary specs are "BFlat" and "BIVF{nlist}".
+//
+// Training parameters such as iterations and seeds are not part of a spec.
+
+const (
+	defaultPQNumBits          = 8
+	defaultHNSWEfConstruction = 40
+	defaultHNSWEfSearch       = 16
+)
+
+// NewIndexFromSpec creates an index from a factory string such as
+// "IVF1024,PQ16x8".
+func NewIndexFromSpec(numFeatures int, spec string) (ANNIndex, error) {
+	builder, err := ParseIndexSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	return NewIndex(numFeatures, builder)
+}
+
+// ParseIndexSpec converts a factory string into the equivalent IndexBuilder.
+func ParseIndexSpec(spec string) (IndexBuilder, error) {
+	if shards, shardSpec, ok := strings.Cut(spec, ":"); ok {
+		return parseShardedSpec(spec, shards, shardSpec)
+	}
+
+	parts := strings.Split(spec, ",")
+
+	var refineOpts []RefineIndexOption
+	kFactor := 0
+	if last := parts[len(parts)-1]; strings.HasPrefix(last, "Refine") {
+		value := strings.TrimPrefix(last, "Refine")
+		if strings.HasSuffix(value, "(SQ8)") {
+			value = strings.TrimSuffix(value, "(SQ8)")
+			refineOpts = append(refineOpts, WithRefineScalarQuantizer())
+		}
+		k, err := parseSpecInt(spec, value)
+		if err != nil {
+			return nil, err
+		}
+		kFactor = k
+		parts = parts[:len(parts)-1]
+	}
+
+	builder, err := parseBaseSpec(spec, parts)
+	if err != nil {
+		return nil, err
+	}
+	if kFactor > 0 {
+		builder = AsRefine(builder, kFactor, refineOpts...)
+	}
+	return builder, nil
+}
+
+func parseShardedSpec(spec, shards, shardSpec string) (IndexBuilder, error) {
+	var opts []ShardedIndexOption
+	value, ok := strings.CutPrefix(shards, "ShardHash")
+	if ok {
+		opts = append(opts, WithShardedHashPartition())
+	} else if value, ok = strings.CutPrefix(shards, "Shard"); !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidIndexSpec, spec)
+	}
+	numShards, err := parseSpecInt(spec, value)
+	if err != nil {
+		return nil, err
+	}
+	builder, err := ParseIndexSpec(shardSpec)
+	if err != nil {
+		return nil, err
+	}
+	return AsSharded(numShards, builder, opts...), nil
+}
+
+func parseBaseSpec(spec string, parts []string) (IndexBuilder, error) {
+	switch {
+	case len(parts) == 1 && parts[0] == "Flat":
+		return AsFlat(), nil
+	case len(parts) == 1 && strings.HasPrefix(parts[0], "PQ"):
+		numSubspaces, numClusters, err := parsePQSpec(spec, parts[0])
+		if err != nil {
+			return nil, err
+		}
+		return AsPQ(numSubspaces, numClusters), nil
+	case len(parts) == 2 && strings.HasPrefix(parts[0], "IVF"):
+		numClusters, opts, err := parseIVFSpec(spec, parts[0])
+		if err != nil {
+			return nil, err
+		}
+		if parts[1] == "Flat" {
+			return AsIVFFlat(numClusters, opts...), nil
+		}
+		if !strings.HasPrefix(parts[1], "PQ") {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidIndexSpec, spec)
+		}
+		numSubspaces, numPqClusters, err := parsePQSpec(spec, parts[1])
+		if err != nil {
+			return nil, err
+		}
+		return AsIVFPQ(numClusters, numSubspaces, numPqClusters, opts...), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrInvalidIndexSpec, spec)
+}
+
+func parsePQSpec(spec, part string) (int, int, error) {
+	value := strings.TrimPrefix(part, "PQ")
+	numBits := defaultPQNumBits
+	numClusters := 0
+	if m, n, ok := strings.Cut(value, "x"); ok {
+		b, err := parseSpecInt(spec, n)
+		if err != nil {
+			return 0, 0, err
+		}
+		if b >= 32 {
+			return 0, 0, fmt.Errorf("%w: %q", ErrInvalidIndexSpec, spec)
+		}
+		value, numBits = m, b
+	} else if m, n, ok := strings.Cut(value, "c"); ok {
+		c, err := parseSpecInt(spec, n)
+		if err != nil {
+			return 0, 0, err
+		}
+		value, numClusters = m, c
+	}
+	numSubspaces, err := parseSpecInt(spec, value)
+	if err != nil {
+		return 0, 0, err
+	}
+	if numClusters == 0 {
+		numClusters = 1 << numBits
+	}
+	return numSubspaces, numClusters, nil
+}
+
+func parseIVFSpec(spec, part string) (int, []InvertedFileIndexOption, error) {
+	value, hnsw, hasHNSW := strings.Cut(strings.TrimPrefix(part, "IVF"), "_HNSW")
+	numClusters, err := parseSpecInt(spec, value)
+	if err != nil {
+		return 0, nil, err
+	}
+	if !hasHNSW {
+		return numClusters, nil, nil
+	}
+
+	params := strings.Split(hnsw, "x")
+	if len(params) != 1 && len(params) != 3 {
+		return 0, nil, fmt.Errorf("%w: %q", ErrInvalidIndexSpec, spec)
+	}
+	values := []int{0, defaultHNSWEfConstruction, defaultHNSWEfSearch}
+	for i, param := range params {
+		values[i], err = parseSpecInt(spec, param)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+	opts := []InvertedFileIndexOption{WithIVFHNSWQuantizer(values[0], values[1], values[2])}
+	return numClusters, opts, nil
+}
+
+func parseSpecInt(spec, value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidIndexSpec, spec)
+	}
+	return n, nil
+}
+
+// NewBinaryIndexFromSpec creates a binary index from a factory string such as
+// "BIVF1024".
+func NewBinaryIndexFromSpec(numBits int, spec string) (BinaryANNIndex, error) {
+	builder, err := ParseBinaryIndexSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	return NewBinaryIndex(numBits, builder)
+}
+
+// ParseBinaryIndexSpec converts a binary factory string into the equivalent
+// BinaryIndexBuilder.
+func ParseBinaryIndexSpec(spec string) (BinaryIndexBuilder, error) {
+	if spec == "BFlat" {
+		return AsBinaryFlat(), nil
+	}
+	if strings.HasPrefix(spec, "BIVF") {
+		numClusters, err := parseSpecInt(spec, strings.TrimPrefix(spec, "BIVF"))
+		if err != nil {
+			return nil, err
+		}
+		return AsBinaryIVF(numClusters), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrInvalidIndexSpec, spec)
+}
+
+func describePQ(numSubspaces, numClusters int) string {
+	if numClusters > 1 && bits.OnesCount(uint(numClusters)) == 1 {
+		return fmt.Sprintf("PQ%dx%d", numSubspaces, bits.TrailingZeros(uint(numClusters)))
+	}
+	return fmt.Sprintf("PQ%dc%d", numSubspaces, numClusters)
+}
+
+func describeIVF(numClusters int, config *InvertedFileIndexConfig) string {
+	desc := fmt.Sprintf("IVF%d", numClusters)
+	if config.HNSWM == 0 {
+		return desc
+	}
+	if config.HNSWEfConstruction == defaultHNSWEfConstruction && config.HNSWEfSearch == defaultHNSWEfSearch {
+		return fmt.Sprintf("%s_HNSW%d", desc, config.HNSWM)
+	}
+	return fmt.Sprintf("%s_HNSW%dx%dx%d", desc, config.HNSWM, config.HNSWEfConstruction, config.HNSWEfSearch)
+}
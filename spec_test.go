@@ -0,0 +1,92 @@
+package vanadium_index
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewIndexFromSpec(t *testing.T) {
+	tests := []struct {
+		spec     string
+		describe string
+	}{
+		{"Flat", "Flat"},
+		{"PQ2x2", "PQ2x2"},
+		{"PQ2", "PQ2x8"},
+		{"PQ2c3", "PQ2c3"},
+		{"IVF4,Flat", "IVF4,Flat"},
+		{"IVF4,PQ2x2", "IVF4,PQ2x2"},
+		{"IVF4_HNSW8,Flat", "IVF4_HNSW8,Flat"},
+		{"IVF4_HNSW8x20x10,PQ2x2", "IVF4_HNSW8x20x10,PQ2x2"},
+		{"PQ2x2,Refine4", "PQ2x2,Refine4"},
+		{"IVF4,PQ2x2,Refine4(SQ8)", "IVF4,PQ2x2,Refine4(SQ8)"},
+	}
+	for _, tt := range tests {
+		index, err := NewIndexFromSpec(4, tt.spec)
+		if err != nil {
+			t.Fatalf("%s: Failed to create index: %v", tt.spec, err)
+		}
+		if index.Describe() != tt.describe {
+			t.Fatalf("%s: expected description %q, got %q", tt.spec, tt.describe, index.Describe())
+		}
+
+		roundTrip, err := NewIndexFromSpec(4, index.Describe())
+		if err != nil {
+			t.Fatalf("%s: Failed to create index from description: %v", tt.spec, err)
+		}
+		if roundTrip.Describe() != index.Describe() {
+			t.Fatalf("%s: expected description %q, got %q", tt.spec, index.Describe(), roundTrip.Describe())
+		}
+	}
+}
+
+func TestNewIndexFromSpecMatchesBuilder(t *testing.T) {
+	index, err := NewIndex(4, AsRefine(AsIVFPQ(4, 2, 16, WithIVFHNSWQuantizer(8, 40, 16)), 2))
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	expected := "IVF4_HNSW8,PQ2x4,Refine2"
+	if index.Describe() != expected {
+		t.Fatalf("expected description %q, got %q", expected, index.Describe())
+	}
+}
+
+func TestNewIndexFromSpecErrors(t *testing.T) {
+	specs := []string{
+		"",
+		"HNSW32",
+		"PQ",
+		"PQ0x8",
+		"PQ2x0",
+		"PQ2x32",
+		"IVF,Flat",
+		"IVF4",
+		"IVF4,IVF4",
+		"IVF4_HNSW8x20,Flat",
+		"Flat,Refine",
+		"Flat,PQ2",
+	}
+	for _, spec := range specs {
+		_, err := NewIndexFromSpec(4, spec)
+		if !errors.Is(err, ErrInvalidIndexSpec) {
+			t.Fatalf("%q: expected %v, got %v", spec, ErrInvalidIndexSpec, err)
+		}
+	}
+}
+
+func TestNewBinaryIndexFromSpec(t *testing.T) {
+	for _, spec := range []string{"BFlat", "BIVF4"} {
+		index, err := NewBinaryIndexFromSpec(16, spec)
+		if err != nil {
+			t.Fatalf("%s: Failed to create index: %v", spec, err)
+		}
+		if index.Describe() != spec {
+			t.Fatalf("expected description %q, got %q", spec, index.Describe())
+		}
+	}
+
+	_, err := NewBinaryIndexFromSpec(16, "Flat")
+	if !errors.Is(err, ErrInvalidIndexSpec) {
+		t.Fatalf("expected %v, got %v", ErrInvalidIndexSpec, err)
+	}
+}
@@ -0,0 +1,51 @@
+"""Writes the FAISS fixtures read by TestReadFAISSIndexFromFAISS.
+
+Run from this directory with the faiss and numpy Python packages:
+
+    python generate.py
+
+For each index type it writes <name>.faiss with faiss.write_index and
+<name>.json with the queries and the ids and distances FAISS returns for
+them. IVF indexes are searched with nprobe=1, as ReadFAISSIndex does. The
+output replaces the fixtures written by write_fixtures.py.
+"""
+
+import json
+
+import faiss
+import numpy as np
+
+d = 8
+k = 5
+rng = np.random.default_rng(1)
+data = rng.random((500, d), dtype=np.float32)
+queries = rng.random((10, d), dtype=np.float32)
+
+indexes = {
+    "flat_l2": faiss.IndexFlatL2(d),
+    "pq": faiss.IndexPQ(d, 4, 8),
+    "ivf_flat": faiss.IndexIVFFlat(faiss.IndexFlatL2(d), d, 4),
+    "ivf_pq": faiss.IndexIVFPQ(faiss.IndexFlatL2(d), d, 4, 4, 8),
+}
+
+for name, index in indexes.items():
+    index.train(data)
+    index.add(data)
+    if isinstance(index, faiss.IndexIVF):
+        index.nprobe = 1
+    distances, ids = index.search(queries, k)
+    # FAISS pads short results with id -1 and an infinite distance, which
+    # JSON cannot hold.
+    distances[ids < 0] = 0
+    faiss.write_index(index, f"{name}.faiss")
+    with open(f"{name}.json", "w") as f:
+        json.dump(
+            {
+                "faiss_version": faiss.__version__,
+                "k": k,
+                "queries": queries.tolist(),
+                "ids": ids.tolist(),
+                "distances": distances.tolist(),
+            },
+            f,
+        )
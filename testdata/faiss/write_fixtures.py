@@ -0,0 +1,224 @@
+"""Writes the fixtures read by TestReadFAISSIndexFromFAISS without FAISS.
+
+generate.py writes the fixtures with FAISS itself and should be preferred.
+This script is for environments where FAISS cannot be installed. It needs
+only the Python standard library:
+
+    python write_fixtures.py
+
+It trains the same index types as generate.py with a plain k-means and lays
+the files out field by field as faiss/impl/index_write.cpp does, independently
+of faiss.go. The expected results are an exhaustive search over the stored
+vectors or their PQ reconstructions, which is what FAISS computes for these
+indexes with nprobe=1. The JSON files record "writer" instead of
+"faiss_version", so that fixtures written by this script can be told apart.
+"""
+
+import json
+import random
+import struct
+
+d = 8
+k = 5
+num_lists = 4
+num_subspaces = 4
+nbits = 8
+rng = random.Random(1)
+
+
+def f32(x):
+    return struct.unpack("<f", struct.pack("<f", x))[0]
+
+
+def random_vectors(n):
+    return [[f32(rng.random()) for _ in range(d)] for _ in range(n)]
+
+
+data = random_vectors(500)
+queries = random_vectors(10)
+
+
+def distance(x, y):
+    return sum((a - b) ** 2 for a, b in zip(x, y))
+
+
+def nearest(x, centroids):
+    return min(range(len(centroids)), key=lambda c: (distance(x, centroids[c]), c))
+
+
+def kmeans(points, num_clusters, iterations=5):
+    centroids = [list(p) for p in rng.sample(points, num_clusters)]
+    for _ in range(iterations):
+        sums = [[0.0] * len(points[0]) for _ in range(num_clusters)]
+        counts = [0] * num_clusters
+        for p in points:
+            c = nearest(p, centroids)
+            counts[c] += 1
+            sums[c] = [s + v for s, v in zip(sums[c], p)]
+        for c in range(num_clusters):
+            if counts[c] > 0:
+                centroids[c] = [f32(s / counts[c]) for s in sums[c]]
+    return centroids
+
+
+def subvectors(x):
+    dsub = d // num_subspaces
+    return [x[m * dsub : (m + 1) * dsub] for m in range(num_subspaces)]
+
+
+def train_pq(points):
+    split = [subvectors(p) for p in points]
+    return [kmeans([s[m] for s in split], 1 << nbits) for m in range(num_subspaces)]
+
+
+def encode(x, codebooks):
+    return [nearest(s, codebooks[m]) for m, s in enumerate(subvectors(x))]
+
+
+def decode(codes, codebooks):
+    return [v for m, c in enumerate(codes) for v in codebooks[m][c]]
+
+
+def search(vectors, ids):
+    results = []
+    for q in queries:
+        found = sorted((distance(q, v), i) for v, i in zip(vectors, ids))[:k]
+        results.append(found)
+    return results
+
+
+class Writer:
+    def __init__(self):
+        self.b = bytearray()
+
+    def fourcc(self, s):
+        self.b += s.encode()
+
+    def pack(self, fmt, *values):
+        self.b += struct.pack("<" + fmt, *values)
+
+    def size(self, v):
+        self.pack("Q", v)
+
+    def floats(self, values):
+        self.pack(f"{len(values)}f", *values)
+
+    def header(self, ntotal):
+        # write_index_header: d, ntotal, two dummies, is_trained, metric_type.
+        self.pack("iqqqBi", d, ntotal, 1 << 20, 1 << 20, 1, 1)
+
+    def flat(self, vectors):
+        self.fourcc("IxF2")
+        self.header(len(vectors))
+        values = [v for x in vectors for v in x]
+        self.size(len(values))
+        self.floats(values)
+
+    def product_quantizer(self, codebooks):
+        self.size(d)
+        self.size(num_subspaces)
+        self.size(nbits)
+        values = [v for codebook in codebooks for c in codebook for v in c]
+        self.size(len(values))
+        self.floats(values)
+
+    def ivf_header(self, centroids):
+        self.header(len(data))
+        self.size(len(centroids))
+        self.size(1)  # nprobe
+        self.flat(centroids)
+        self.pack("B", 0)  # direct map type: NoMap
+        self.size(0)  # direct map array
+
+    def inverted_lists(self, lists, code_size):
+        self.fourcc("ilar")
+        self.size(len(lists))
+        self.size(code_size)
+        self.fourcc("full")  # every list holds vectors
+        self.size(len(lists))
+        for codes, ids in lists:
+            self.size(len(ids))
+        for codes, ids in lists:
+            self.b += codes
+            self.pack(f"{len(ids)}q", *ids)
+
+
+def write(name, writer, results):
+    with open(f"{name}.faiss", "wb") as f:
+        f.write(writer.b)
+    with open(f"{name}.json", "w") as f:
+        json.dump(
+            {
+                "writer": "write_fixtures.py",
+                "k": k,
+                "queries": queries,
+                "ids": [[i for _, i in found] for found in results],
+                "distances": [[dist for dist, _ in found] for found in results],
+            },
+            f,
+        )
+
+
+ids = list(range(len(data)))
+
+w = Writer()
+w.flat(data)
+write("flat_l2", w, search(data, ids))
+
+codebooks = train_pq(data)
+codes = [encode(x, codebooks) for x in data]
+w = Writer()
+w.fourcc("IxPq")
+w.header(len(data))
+w.product_quantizer(codebooks)
+w.size(len(data) * num_subspaces)
+w.b += bytes(c for x in codes for c in x)
+w.pack("iBi", 0, 0, 0)  # search_type, encode_signs, polysemous_ht
+write("pq", w, search([decode(c, codebooks) for c in codes], ids))
+
+centroids = kmeans(data, num_lists)
+assignments = [nearest(x, centroids) for x in data]
+
+
+def lists_of(encode_vector):
+    lists = []
+    for c in range(num_lists):
+        members = [i for i in ids if assignments[i] == c]
+        codes = b"".join(encode_vector(i) for i in members)
+        lists.append((codes, members))
+    return lists
+
+
+def list_search(reconstruct):
+    results = []
+    for q in queries:
+        c = nearest(q, centroids)
+        members = [i for i in ids if assignments[i] == c]
+        found = sorted((distance(q, reconstruct(i)), i) for i in members)[:k]
+        results.append(found)
+    return results
+
+
+w = Writer()
+w.fourcc("IwFl")
+w.ivf_header(centroids)
+w.inverted_lists(lists_of(lambda i: struct.pack(f"<{d}f", *data[i])), d * 4)
+write("ivf_flat", w, list_search(lambda i: data[i]))
+
+residuals = [[f32(v - c) for v, c in zip(x, centroids[assignments[i]])] for i, x in enumerate(data)]
+codebooks = train_pq(residuals)
+codes = [encode(r, codebooks) for r in residuals]
+w = Writer()
+w.fourcc("IwPQ")
+w.ivf_header(centroids)
+w.pack("B", 1)  # by_residual
+w.size(num_subspaces)  # code_size
+w.product_quantizer(codebooks)
+w.inverted_lists(lists_of(lambda i: bytes(codes[i])), num_subspaces)
+write(
+    "ivf_pq",
+    w,
+    list_search(
+        lambda i: [f32(c + r) for c, r in zip(centroids[assignments[i]], decode(codes[i], codebooks))]
+    ),
+)
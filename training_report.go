@@ -0,0 +1,43 @@
+package vanadium_index
+
+// ClusteringReport describes how a single kmeans model was trained.
+type ClusteringReport struct {
+	Iterations    int
+	Inertia       float32
+	EmptyClusters int
+	Converged     bool
+}
+
+// TrainingReport describes how an index was trained. Coarse is set for IVF
+// indexes, Subspaces for PQ indexes, and Lists holds the report of each
+// per-list PQ index of an IVF-PQ index. FallbackLists are the lists that had
+// too few training vectors for their own PQ codebooks and share codebooks
+// trained on all training data instead. MSE is the mean squared quantization
+// error per training vector.
+type TrainingReport struct {
+	NumVectors    int
+	Coarse        *ClusteringReport
+	Subspaces     []ClusteringReport
+	Lists         []*TrainingReport
+	FallbackLists []int
+	MSE           float32
+}
+
+type TrainingCallback func(report *TrainingReport)
+
+// newClusteringReport builds the report of a kmeans model from the values
+// returned by its training and the number of training vectors assigned to
+// each cluster.
+func newClusteringReport(numIter int, inertia float32, converged bool, counts []int) ClusteringReport {
+	report := ClusteringReport{
+		Iterations: numIter + 1,
+		Inertia:    inertia,
+		Converged:  converged,
+	}
+	for _, count := range counts {
+		if count == 0 {
+			report.EmptyClusters++
+		}
+	}
+	return report
+}
@@ -0,0 +1,142 @@
+package vanadium_index
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// VectorReader yields vectors for AddFrom. Read fills buf with float32 values
+// and returns how many it wrote, following the io.Reader conventions: it
+// returns io.EOF once no values remain.
+type VectorReader interface {
+	Read(buf []float32) (int, error)
+}
+
+type AddFromOption func(*AddFromConfig) error
+
+type AddFromConfig struct {
+	BatchSize int
+	Progress  func(numAdded int)
+}
+
+// WithAddFromBatchSize sets the number of vectors passed to each Add call.
+func WithAddFromBatchSize(batchSize int) AddFromOption {
+	return func(config *AddFromConfig) error {
+		if batchSize <= 0 {
+			return ErrInvalidBatchSize
+		}
+		config.BatchSize = batchSize
+		return nil
+	}
+}
+
+// WithAddFromProgress calls fn after each batch with the total number of
+// vectors added so far.
+func WithAddFromProgress(fn func(numAdded int)) AddFromOption {
+	return func(config *AddFromConfig) error {
+		config.Progress = fn
+		return nil
+	}
+}
+
+// AddFrom adds every vector from reader to index, reading at most one batch
+// into memory at a time. It returns the number of vectors added, which
+// includes the batches added before an error.
+func AddFrom(index ANNIndex, reader VectorReader, opts ...AddFromOption) (int, error) {
+	config := &AddFromConfig{
+		BatchSize: 1024,
+	}
+	for _, opt := range opts {
+		err := opt(config)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	numFeatures := index.NumFeatures()
+	buf := make([]float32, config.BatchSize*numFeatures)
+	numAdded := 0
+	for {
+		n, err := readFull(reader, buf)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return numAdded, err
+		}
+		if n > 0 {
+			if n%numFeatures != 0 {
+				return numAdded, newIndexError(OpAdd, index.Info().IndexType, ErrInvalidDataLength, "dataLength", numAdded*numFeatures+n, "numFeatures", numFeatures)
+			}
+			addErr := index.Add(buf[:n])
+			if addErr != nil {
+				return numAdded, addErr
+			}
+			numAdded += n / numFeatures
+			if config.Progress != nil {
+				config.Progress(numAdded)
+			}
+		}
+		if err != nil {
+			return numAdded, nil
+		}
+	}
+}
+
+func readFull(reader VectorReader, buf []float32) (int, error) {
+	n := 0
+	for n < len(buf) {
+		m, err := reader.Read(buf[n:])
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+type float32Reader struct {
+	r   io.Reader
+	buf []byte
+}
+
+// NewFloat32Reader reads vectors stored as consecutive little-endian float32
+// values, the layout of a raw embedding dump.
+func NewFloat32Reader(r io.Reader) VectorReader {
+	return &float32Reader{r: r}
+}
+
+func (reader *float32Reader) Read(buf []float32) (int, error) {
+	if cap(reader.buf) < len(buf)*4 {
+		reader.buf = make([]byte, len(buf)*4)
+	}
+	bytes := reader.buf[:len(buf)*4]
+	n, err := io.ReadFull(reader.r, bytes)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		if n%4 != 0 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		err = io.EOF
+	}
+	for i := range n / 4 {
+		buf[i] = math.Float32frombits(binary.LittleEndian.Uint32(bytes[i*4:]))
+	}
+	return n / 4, err
+}
+
+type sliceReader struct {
+	data []float32
+}
+
+// NewSliceReader yields the vectors of data.
+func NewSliceReader(data []float32) VectorReader {
+	return &sliceReader{data: data}
+}
+
+func (reader *sliceReader) Read(buf []float32) (int, error) {
+	if len(reader.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(buf, reader.data)
+	reader.data = reader.data[n:]
+	return n, nil
+}
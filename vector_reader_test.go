@@ -0,0 +1,107 @@
+package vanadium_index
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestAddFrom(t *testing.T) {
+	numFeatures := 4
+	data := []float32{
+		0.1, 0.2, 0.3, 0.4,
+		0.5, 0.6, 0.7, 0.8,
+		0.9, 1.0, 1.1, 1.2,
+		1.3, 1.4, 1.5, 1.6,
+		1.7, 1.8, 1.9, 2.0,
+	}
+
+	index, err := NewIndex(numFeatures, AsPQ(2, 2, WithPQMaxIterations(10)))
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	err = index.Train(data)
+	if err != nil {
+		t.Fatalf("Failed to train index: %v", err)
+	}
+
+	progress := []int{}
+	numAdded, err := AddFrom(index, NewSliceReader(data),
+		WithAddFromBatchSize(2),
+		WithAddFromProgress(func(numAdded int) {
+			progress = append(progress, numAdded)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	if numAdded != 5 || index.NumVectors() != 5 {
+		t.Fatalf("expected 5 vectors, got %d added and %d in index", numAdded, index.NumVectors())
+	}
+	expected := []int{2, 4, 5}
+	if len(progress) != len(expected) {
+		t.Fatalf("expected progress %v, got %v", expected, progress)
+	}
+	for i := range expected {
+		if progress[i] != expected[i] {
+			t.Fatalf("expected progress %v, got %v", expected, progress)
+		}
+	}
+
+	batchIndex, _ := NewIndex(numFeatures, AsPQ(2, 2, WithPQMaxIterations(10)))
+	err = batchIndex.(*ProductQuantizationIndex[uint8]).shareModel(index.(*ProductQuantizationIndex[uint8]))
+	if err != nil {
+		t.Fatalf("Failed to share model: %v", err)
+	}
+	err = batchIndex.Add(data)
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	streamed := index.(*ProductQuantizationIndex[uint8]).state.Codes
+	batched := batchIndex.(*ProductQuantizationIndex[uint8]).state.Codes
+	for i := range batched {
+		if streamed[i] != batched[i] {
+			t.Fatalf("expected codes %v, got %v", batched, streamed)
+		}
+	}
+}
+
+func TestAddFromFloat32Reader(t *testing.T) {
+	data := []float32{0.1, 0.2, 0.3, 0.4, 0.5, 0.6}
+	var buf bytes.Buffer
+	for _, value := range data {
+		binary.Write(&buf, binary.LittleEndian, math.Float32bits(value))
+	}
+
+	index, _ := NewIndex(2, AsFlat())
+	numAdded, err := AddFrom(index, NewFloat32Reader(&buf), WithAddFromBatchSize(2))
+	if err != nil {
+		t.Fatalf("Failed to add data: %v", err)
+	}
+	if numAdded != 3 {
+		t.Fatalf("expected 3 vectors, got %d", numAdded)
+	}
+	for i, value := range index.(*FlatIndex).state.Data {
+		if value != data[i] {
+			t.Fatalf("expected %v, got %v", data, index.(*FlatIndex).state.Data)
+		}
+	}
+}
+
+func TestAddFromErrors(t *testing.T) {
+	index, _ := NewIndex(2, AsFlat())
+	numAdded, err := AddFrom(index, NewSliceReader([]float32{0.1, 0.2, 0.3}), WithAddFromBatchSize(4))
+	if !errors.Is(err, ErrInvalidDataLength) {
+		t.Fatalf("expected %v, got %v", ErrInvalidDataLength, err)
+	}
+	if numAdded != 0 {
+		t.Fatalf("expected 0 vectors, got %d", numAdded)
+	}
+
+	_, err = AddFrom(index, NewSliceReader([]float32{0.1, 0.2}), WithAddFromBatchSize(0))
+	if !errors.Is(err, ErrInvalidBatchSize) {
+		t.Fatalf("expected %v, got %v", ErrInvalidBatchSize, err)
+	}
+}